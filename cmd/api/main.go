@@ -5,8 +5,11 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"streamvault/internal/api"
+	"streamvault/internal/models"
 	"streamvault/internal/storage"
 
 	"github.com/joho/godotenv"
@@ -23,6 +26,20 @@ func main() {
 	dbHost := os.Getenv("DB_HOST")
 	jwtSecret := os.Getenv("JWT_SECRET")
 	uploadDir := os.Getenv("UPLOAD_DIR")
+	// Cuota de subida por defecto para los usuarios normales. Los admins no tienen límite.
+	userMaxBytes := envInt64("QUOTA_USER_MAX_BYTES", 2<<30)
+	userMaxVideos := int(envInt64("QUOTA_USER_MAX_VIDEOS", 20))
+	// Días que un video borrado pasa en la papelera antes de eliminarse definitivamente.
+	trashRetentionDays := envInt64("TRASH_RETENTION_DAYS", 30)
+	// Tamaño máximo de un video subido, en bytes (por defecto 10 GB).
+	maxUploadSize := envInt64("MAX_UPLOAD_BYTES", 10<<30)
+	// Con "quarantine" la reconciliación diaria mueve los archivos huérfanos a UPLOAD_DIR/.quarantine.
+	quarantineOrphans := os.Getenv("ORPHAN_FILES") == "quarantine"
+	// Carpeta vigilada para importar videos automáticamente (vacía la desactiva).
+	watchDir := os.Getenv("WATCH_DIR")
+	watchPollSeconds := envInt64("WATCH_POLL_SECONDS", 30)
+	// Carpeta desde la que se pueden importar bibliotecas con NFO (vacía la desactiva).
+	libraryDir := os.Getenv("LIBRARY_DIR")
 
 	psqlInfo := fmt.Sprintf("host=%s port=5432 user=%s password=%s dbname=%s sslmode=disable",
 		dbHost, dbUser, dbPassword, dbName)
@@ -47,11 +64,35 @@ func main() {
 		Store:     store,
 		UploadDir: uploadDir,
 		JwtSecret: jwtSecret,
+		RoleQuotas: map[string]models.Quota{
+			"user": {MaxBytes: &userMaxBytes, MaxVideos: &userMaxVideos},
+		},
+		TrashRetention:    time.Duration(trashRetentionDays) * 24 * time.Hour,
+		MaxUploadSize:     maxUploadSize,
+		QuarantineOrphans: quarantineOrphans,
+		WatchDir:          watchDir,
+		WatchInterval:     time.Duration(watchPollSeconds) * time.Second,
+		LibraryDir:        libraryDir,
 	}
 
+	// Lanza las tareas periódicas (agregado de estadísticas, etc.).
+	app.StartBackgroundJobs()
+
 	r := api.NewRouter(app)
 
 	port := "8080"
 	fmt.Printf("Servidor escuchando en el puerto :%s\n", port)
 	log.Fatal(http.ListenAndServe(":"+port, r))
 }
+
+// envInt64 lee una variable de entorno numérica, usando 'def' si falta o no es un número válido.
+func envInt64(name string, def int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(name), 10, 64)
+	if err != nil {
+		if os.Getenv(name) != "" {
+			log.Printf("Advertencia: %s no es un número válido, se usa %d", name, def)
+		}
+		return def
+	}
+	return value
+}
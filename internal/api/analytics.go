@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"streamvault/internal/models"
+	"streamvault/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// viewDedupWindow es el tiempo durante el cual las peticiones del mismo espectador sobre el
+// mismo video se consideran una única vista (por ejemplo, las peticiones Range al hacer seek).
+const viewDedupWindow = 30 * time.Minute
+
+// maxBeaconSeconds limita cuánto tiempo de reproducción puede declarar un solo beacon,
+// para que un cliente no pueda inflar las estadísticas con un valor arbitrario.
+const maxBeaconSeconds = 300
+
+// viewerIdentity identifica al espectador: por su ID si inició sesión, o por su IP si es anónimo.
+func viewerIdentity(r *http.Request) (userID int, key string) {
+	if claims, ok := claimsFromContext(r); ok && claims.UserID != 0 {
+		return claims.UserID, "user:" + strconv.Itoa(claims.UserID)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return 0, "ip:" + host
+}
+
+// recordStreamView cuenta una vista del video que está por servir HandleStreamVideo.
+// Los errores solo se registran en el log: las analíticas nunca deben interrumpir la reproducción.
+func (h *handler) recordStreamView(r *http.Request, video *models.Video) {
+	userID, key := viewerIdentity(r)
+	if err := h.app.Store.RecordView(video.ID, userID, key, viewDedupWindow); err != nil {
+		log.Printf("[Video ID: %d] Error al registrar la vista: %v", video.ID, err)
+	}
+}
+
+// HandleVideoBeacon recibe los latidos periódicos del reproductor con el tiempo visto desde el
+// último beacon, la posición actual y la duración total del video. El beacon se suma a la sesión
+// que abrió el stream del mismo espectador; sin una sesión abierta, o si declara más tiempo que
+// el transcurrido desde el beacon anterior, se rechaza.
+func (h *handler) HandleVideoBeacon(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	var payload struct {
+		WatchedSeconds float64 `json:"watched_seconds"`
+		Position       float64 `json:"position"`
+		Duration       float64 `json:"duration"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Cuerpo de la petición inválido")
+		return
+	}
+	if payload.WatchedSeconds < 0 || payload.Position < 0 || payload.Duration < 0 {
+		respondWithError(w, http.StatusBadRequest, "Los valores no pueden ser negativos")
+		return
+	}
+	if payload.WatchedSeconds > maxBeaconSeconds {
+		payload.WatchedSeconds = maxBeaconSeconds
+	}
+	video, err := h.app.Store.GetVideoByID(id)
+	if err != nil || !canSeeVideo(r, video) {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	// Si ya se analizó el archivo, la duración es la real y no la que declara el cliente.
+	if video.Media.DurationSeconds > 0 {
+		payload.Duration = video.Media.DurationSeconds
+	}
+	_, key := viewerIdentity(r)
+	err = h.app.Store.RecordWatchProgress(id, key, viewDedupWindow, payload.WatchedSeconds, payload.Position, payload.Duration)
+	switch {
+	case errors.Is(err, storage.ErrNoViewSession):
+		respondWithError(w, http.StatusConflict, "No hay una reproducción en curso de este video")
+		return
+	case errors.Is(err, storage.ErrWatchTimeExceeded):
+		respondWithError(w, http.StatusBadRequest, "El tiempo visto supera el tiempo transcurrido desde el último beacon")
+		return
+	case err != nil:
+		respondWithError(w, http.StatusInternalServerError, "Error al registrar el progreso")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleVideoStats devuelve las analíticas de un video (solo para admins).
+// Acepta 'from' y 'to' (AAAA-MM-DD, ambos incluidos; por defecto los últimos 30 días)
+// y 'granularity' ('day' por defecto, o 'hour').
+func (h *handler) HandleVideoStats(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	query := r.URL.Query()
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if v := query.Get("to"); v != "" {
+		if to, err = time.Parse("2006-01-02", v); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Fecha 'to' inválida, usa AAAA-MM-DD")
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -29)
+	if v := query.Get("from"); v != "" {
+		if from, err = time.Parse("2006-01-02", v); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Fecha 'from' inválida, usa AAAA-MM-DD")
+			return
+		}
+	}
+	if from.After(to) {
+		respondWithError(w, http.StatusBadRequest, "'from' no puede ser posterior a 'to'")
+		return
+	}
+	granularity := query.Get("granularity")
+	if granularity == "" {
+		granularity = "day"
+	}
+	if granularity != "day" && granularity != "hour" {
+		respondWithError(w, http.StatusBadRequest, "Granularidad inválida. Debe ser 'day' u 'hour'.")
+		return
+	}
+	if _, err := h.app.Store.GetVideoByID(id); err != nil {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	// 'to' se incluye completo, así que la consulta llega hasta el inicio del día siguiente.
+	stats, err := h.app.Store.GetVideoStats(id, from, to.AddDate(0, 0, 1), granularity)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al obtener las estadísticas")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"streamvault/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// bookmarkLists traduce el segmento de la URL al nombre de la lista guardado en la BD.
+var bookmarkLists = map[string]string{
+	"favorites":   models.BookmarkFavorites,
+	"watch-later": models.BookmarkWatchLater,
+}
+
+// bookmarkRequest extrae el usuario autenticado y la lista pedida en la ruta.
+// Si algo falla, ya escribe la respuesta de error y devuelve ok = false.
+func bookmarkRequest(w http.ResponseWriter, r *http.Request) (userID int, list string, ok bool) {
+	claims, ok := claimsFromContext(r)
+	if !ok || claims.UserID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "Sesión inválida, vuelve a iniciar sesión")
+		return 0, "", false
+	}
+	list, ok = bookmarkLists[mux.Vars(r)["list"]]
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Lista no encontrada")
+		return 0, "", false
+	}
+	return claims.UserID, list, true
+}
+
+// HandleListBookmarks devuelve, paginados, los videos de una lista del usuario.
+func (h *handler) HandleListBookmarks(w http.ResponseWriter, r *http.Request) {
+	userID, list, ok := bookmarkRequest(w, r)
+	if !ok {
+		return
+	}
+	page, limit := parsePagination(r)
+	videos, total, err := h.app.Store.GetBookmarkedVideos(userID, list, limit, (page-1)*limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "No se pudo obtener la lista")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, paginatedVideos{Videos: videos, Page: page, Limit: limit, Total: total})
+}
+
+// HandleAddBookmark agrega un video a una lista del usuario.
+func (h *handler) HandleAddBookmark(w http.ResponseWriter, r *http.Request) {
+	userID, list, ok := bookmarkRequest(w, r)
+	if !ok {
+		return
+	}
+	videoID, err := strconv.Atoi(mux.Vars(r)["videoId"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	if video, err := h.app.Store.GetVideoByID(videoID); err != nil || !canSeeVideo(r, video) {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	if err := h.app.Store.AddBookmark(userID, videoID, list); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al agregar el video a la lista")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Video agregado a la lista"})
+}
+
+// HandleRemoveBookmark quita un video de una lista del usuario.
+func (h *handler) HandleRemoveBookmark(w http.ResponseWriter, r *http.Request) {
+	userID, list, ok := bookmarkRequest(w, r)
+	if !ok {
+		return
+	}
+	videoID, err := strconv.Atoi(mux.Vars(r)["videoId"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	if err := h.app.Store.RemoveBookmark(userID, videoID, list); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al quitar el video de la lista")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Video quitado de la lista"})
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"streamvault/internal/models"
+)
+
+// maxBulkIDs limita la cantidad de IDs por petición para que la transacción no crezca sin control.
+const maxBulkIDs = 1000
+
+// HandleBulkVideos aplica una operación a muchos videos a la vez (solo para admins).
+// Acciones: delete (mueve a la papelera), set_category, add_tags, remove_tags y set_visibility ('public' o 'hidden').
+// Con "dry_run": true se ejecuta todo y se informa el resultado, pero nada se guarda.
+func (h *handler) HandleBulkVideos(w http.ResponseWriter, r *http.Request) {
+	var req models.BulkVideoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Cuerpo de la petición inválido")
+		return
+	}
+	if len(req.IDs) > maxBulkIDs {
+		respondWithError(w, http.StatusBadRequest, "Demasiados IDs en una sola petición")
+		return
+	}
+	f := req.Filter
+	if len(req.IDs) == 0 && (f == nil || (f.Category == "" && f.Tag == "" && f.UploaderID == nil && f.TitleContains == "")) {
+		respondWithError(w, http.StatusBadRequest, "Debes indicar 'ids' o al menos un criterio en 'filter'")
+		return
+	}
+	req.Tags = normalizeTags(req.Tags)
+	switch req.Action {
+	case "delete":
+	case "set_category":
+		if req.Category == "" {
+			respondWithError(w, http.StatusBadRequest, "Falta el campo 'category'")
+			return
+		}
+	case "add_tags", "remove_tags":
+		if len(req.Tags) == 0 {
+			respondWithError(w, http.StatusBadRequest, "Falta el campo 'tags'")
+			return
+		}
+	case "set_visibility":
+		if req.Visibility != "public" && req.Visibility != "hidden" {
+			respondWithError(w, http.StatusBadRequest, "Visibilidad inválida. Debe ser 'public' o 'hidden'.")
+			return
+		}
+	default:
+		respondWithError(w, http.StatusBadRequest, "Acción inválida. Debe ser 'delete', 'set_category', 'add_tags', 'remove_tags' o 'set_visibility'.")
+		return
+	}
+
+	claims, _ := claimsFromContext(r)
+	report, err := h.app.Store.BulkUpdateVideos(&req, claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al ejecutar la operación masiva")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// HandleBulkUsers aplica una operación a muchos usuarios a la vez (solo para admins).
+// Acciones: delete y set_role. La cuenta del admin que la ejecuta siempre se omite.
+func (h *handler) HandleBulkUsers(w http.ResponseWriter, r *http.Request) {
+	var req models.BulkUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Cuerpo de la petición inválido")
+		return
+	}
+	if len(req.IDs) > maxBulkIDs {
+		respondWithError(w, http.StatusBadRequest, "Demasiados IDs en una sola petición")
+		return
+	}
+	f := req.Filter
+	if len(req.IDs) == 0 && (f == nil || (f.Role == "" && f.UsernameContains == "" && f.EmailContains == "")) {
+		respondWithError(w, http.StatusBadRequest, "Debes indicar 'ids' o al menos un criterio en 'filter'")
+		return
+	}
+	switch req.Action {
+	case "delete":
+	case "set_role":
+		if req.Role != "user" && req.Role != "admin" {
+			respondWithError(w, http.StatusBadRequest, "Rol inválido. Debe ser 'user' o 'admin'.")
+			return
+		}
+	default:
+		respondWithError(w, http.StatusBadRequest, "Acción inválida. Debe ser 'delete' o 'set_role'.")
+		return
+	}
+
+	claims, _ := claimsFromContext(r)
+	report, err := h.app.Store.BulkUpdateUsers(&req, claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al ejecutar la operación masiva")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
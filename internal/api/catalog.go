@@ -0,0 +1,320 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"streamvault/internal/models"
+)
+
+// maxCatalogImportSize limita el tamaño del archivo de importación.
+const maxCatalogImportSize = 20 << 20
+
+// externalIDRe valida los identificadores externos: letras, números y algunos separadores.
+var externalIDRe = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,100}$`)
+
+// catalogCSVColumns son las columnas de la exportación de videos en CSV. 'size_bytes' y
+// 'uploaded_at' son informativas y se ignoran al importar.
+var catalogCSVColumns = []string{"external_id", "title", "description", "category", "tags", "file_path",
+	"size_bytes", "publish_at", "expire_at", "uploaded_at"}
+
+// HandleExportCatalog exporta los metadatos del catálogo (solo para admins). Los videos de la
+// papelera no se incluyen. StreamVault todavía no tiene listas de reproducción, así que no se exportan.
+//
+//	?format=json (por defecto): un documento con videos, categorías y etiquetas.
+//	?format=csv&type=videos|categories|tags: una tabla por tipo, pensada para editar en una planilla.
+func (h *handler) HandleExportCatalog(w http.ResponseWriter, r *http.Request) {
+	videos, err := h.app.Store.GetAllVideos()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "No se pudieron obtener los videos")
+		return
+	}
+	sort.Slice(videos, func(i, j int) bool { return videos[i].ID < videos[j].ID })
+	catalog := models.Catalog{ExportedAt: time.Now().UTC(), Videos: []models.CatalogVideo{}}
+	categories := map[string]int{}
+	tags := map[string]int{}
+	for _, v := range videos {
+		uploadedAt := v.UploadedAt
+		catalog.Videos = append(catalog.Videos, models.CatalogVideo{
+			ExternalID:  v.ExternalID,
+			Title:       v.Title,
+			Description: v.Description,
+			Category:    v.Category,
+			Tags:        v.Tags,
+			FilePath:    v.FilePath,
+			SizeBytes:   v.SizeBytes,
+			PublishAt:   v.PublishAt,
+			ExpireAt:    v.ExpireAt,
+			UploadedAt:  &uploadedAt,
+		})
+		categories[v.Category]++
+		for _, tag := range v.Tags {
+			tags[tag]++
+		}
+	}
+	catalog.Categories = catalogTerms(categories)
+	catalog.Tags = catalogTerms(tags)
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Disposition", `attachment; filename="catalog.json"`)
+		respondWithJSON(w, http.StatusOK, catalog)
+	case "csv":
+		var header []string
+		var records [][]string
+		switch kind := r.URL.Query().Get("type"); kind {
+		case "", "videos":
+			header = catalogCSVColumns
+			for _, v := range catalog.Videos {
+				records = append(records, []string{v.ExternalID, v.Title, v.Description, v.Category,
+					strings.Join(v.Tags, ","), v.FilePath, strconv.FormatInt(v.SizeBytes, 10),
+					formatCatalogTime(v.PublishAt), formatCatalogTime(v.ExpireAt), formatCatalogTime(v.UploadedAt)})
+			}
+		case "categories", "tags":
+			header = []string{"name", "video_count"}
+			terms := catalog.Categories
+			if kind == "tags" {
+				terms = catalog.Tags
+			}
+			for _, t := range terms {
+				records = append(records, []string{t.Name, strconv.Itoa(t.VideoCount)})
+			}
+		default:
+			respondWithError(w, http.StatusBadRequest, "Tipo inválido. Debe ser 'videos', 'categories' o 'tags'.")
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="catalog.csv"`)
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		cw.WriteAll(records)
+	default:
+		respondWithError(w, http.StatusBadRequest, "Formato inválido. Debe ser 'json' o 'csv'.")
+	}
+}
+
+// catalogTerms ordena por nombre los conteos de categorías o etiquetas.
+func catalogTerms(counts map[string]int) []models.CatalogTerm {
+	terms := make([]models.CatalogTerm, 0, len(counts))
+	for name, count := range counts {
+		terms = append(terms, models.CatalogTerm{Name: name, VideoCount: count})
+	}
+	sort.Slice(terms, func(i, j int) bool { return terms[i].Name < terms[j].Name })
+	return terms
+}
+
+func formatCatalogTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// HandleImportCatalog crea o actualiza videos a partir de un catálogo exportado (solo para admins).
+// Cada video se identifica por 'external_id': si existe se actualizan sus metadatos, y si no se crea
+// apuntando a 'file_path', que ya debe estar en UPLOAD_DIR. Los archivos nunca se copian ni se modifican.
+// Al actualizar solo se cambian los campos que trae el archivo: una columna del CSV o una clave
+// del JSON que falta conserva el valor actual (por ejemplo, las fechas de publicación).
+// Si alguna fila tiene errores no se aplica ninguna; con ?dry_run=true solo se informa qué pasaría.
+func (h *handler) HandleImportCatalog(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	format := r.URL.Query().Get("format")
+	if format == "" && strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+		format = "csv"
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxCatalogImportSize)
+
+	var rows []models.CatalogVideo
+	var rowErrors []string
+	var err error
+	switch format {
+	case "", "json":
+		var catalog models.Catalog
+		err = json.NewDecoder(r.Body).Decode(&catalog)
+		rows = catalog.Videos
+		rowErrors = make([]string, len(rows))
+	case "csv":
+		rows, rowErrors, err = parseCatalogCSV(r.Body)
+	default:
+		respondWithError(w, http.StatusBadRequest, "Formato inválido. Debe ser 'json' o 'csv'.")
+		return
+	}
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "El archivo de importación es demasiado grande (límite 20MB)")
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, "Archivo de importación inválido: "+err.Error())
+		return
+	}
+	if len(rows) == 0 {
+		respondWithError(w, http.StatusBadRequest, "El archivo no contiene videos")
+		return
+	}
+
+	// Primero se validan las filas sin tocar la BD; solo las válidas llegan al store.
+	seen := map[string]int{}
+	var valid []models.CatalogVideo
+	var validRows []int
+	for i := range rows {
+		if rowErrors[i] == "" {
+			rowErrors[i] = h.validateCatalogVideo(&rows[i])
+		}
+		if rowErrors[i] == "" {
+			if first, dup := seen[rows[i].ExternalID]; dup {
+				rowErrors[i] = fmt.Sprintf("'external_id' repetido (ya aparece en la fila %d)", first)
+			} else {
+				seen[rows[i].ExternalID] = i + 1
+			}
+		}
+		if rowErrors[i] == "" {
+			valid = append(valid, rows[i])
+			validRows = append(validRows, i)
+		}
+	}
+
+	claims, _ := claimsFromContext(r)
+	// Con errores de validación la importación no se aplica, pero el store igual informa qué
+	// pasaría con las filas válidas.
+	storeDryRun := dryRun || len(valid) < len(rows)
+	report := &models.ImportReport{DryRun: dryRun, Results: make([]models.ImportRowResult, len(rows))}
+	for i, row := range rows {
+		report.Results[i] = models.ImportRowResult{Row: i + 1, ExternalID: row.ExternalID, Status: models.ImportStatusError, Message: rowErrors[i]}
+	}
+	if len(valid) > 0 {
+		stored, err := h.app.Store.ImportVideos(valid, storeDryRun, claims.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error al importar el catálogo")
+			return
+		}
+		for j, result := range stored.Results {
+			result.Row = validRows[j] + 1
+			report.Results[validRows[j]] = result
+		}
+		report.Applied = stored.Applied
+	}
+	if report.Applied {
+		for _, result := range report.Results {
+			if result.Status != models.ImportStatusCreated && result.Status != models.ImportStatusUpdated {
+				continue
+			}
+			if video, err := h.app.Store.GetVideoByID(result.VideoID); err == nil {
+				h.app.syncDescriptionChapters(video)
+			}
+		}
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// validateCatalogVideo normaliza y valida una fila del catálogo. Devuelve el mensaje de error
+// de la fila, o una cadena vacía si es válida.
+func (h *handler) validateCatalogVideo(row *models.CatalogVideo) string {
+	row.ExternalID = strings.TrimSpace(row.ExternalID)
+	row.Title = strings.TrimSpace(row.Title)
+	row.Category = strings.TrimSpace(row.Category)
+	row.Tags = normalizeTags(row.Tags)
+	if !externalIDRe.MatchString(row.ExternalID) {
+		return "'external_id' inválido: usa de 1 a 100 letras, números o los caracteres . _ : -"
+	}
+	if row.Title == "" || row.Category == "" {
+		return "Faltan los campos 'title' o 'category'"
+	}
+	if err := validateSchedule(&models.Video{PublishAt: row.PublishAt, ExpireAt: row.ExpireAt}); err != nil {
+		return err.Error()
+	}
+	// El archivo solo se referencia: debe estar ya en UPLOAD_DIR y se usa su tamaño real.
+	row.SizeBytes = 0
+	if row.FilePath != "" {
+		if row.FilePath != filepath.Base(row.FilePath) || strings.HasPrefix(row.FilePath, ".") {
+			return "'file_path' debe ser un nombre de archivo dentro de UPLOAD_DIR"
+		}
+		info, err := os.Stat(filepath.Join(h.app.UploadDir, row.FilePath))
+		if err != nil || !info.Mode().IsRegular() {
+			return fmt.Sprintf("el archivo '%s' no existe en UPLOAD_DIR", row.FilePath)
+		}
+		row.SizeBytes = info.Size()
+	}
+	return ""
+}
+
+// parseCatalogCSV lee una tabla de videos con encabezados. Las columnas pueden venir en cualquier
+// orden y solo 'external_id', 'title' y 'category' son obligatorias; al actualizar un video, las
+// columnas que faltan conservan el valor que ya tenía. Devuelve también, por fila,
+// los errores de formato (como una fecha inválida) para informarlos junto con los de validación.
+func parseCatalogCSV(body io.Reader) ([]models.CatalogVideo, []string, error) {
+	reader := csv.NewReader(body)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("no se pudo leer la fila de encabezados: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(catalogCSVColumns, name) {
+			return nil, nil, fmt.Errorf("columna desconocida '%s'", name)
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"external_id", "title", "category"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("falta la columna '%s'", required)
+		}
+	}
+
+	var rows []models.CatalogVideo
+	var rowErrors []string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var row models.CatalogVideo
+		if err != nil {
+			// Una fila con otra cantidad de columnas se informa y se sigue con la siguiente.
+			if errors.Is(err, csv.ErrFieldCount) {
+				rows = append(rows, row)
+				rowErrors = append(rowErrors, fmt.Sprintf("la fila tiene %d columnas y el encabezado %d", len(record), len(header)))
+				continue
+			}
+			return nil, nil, err
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return record[i]
+			}
+			return ""
+		}
+		row.Provided = make(map[string]bool, len(columns))
+		for name := range columns {
+			row.Provided[name] = true
+		}
+		row.ExternalID = field("external_id")
+		row.Title = field("title")
+		row.Description = field("description")
+		row.Category = field("category")
+		row.Tags = strings.Split(field("tags"), ",")
+		row.FilePath = strings.TrimSpace(field("file_path"))
+		message := ""
+		if row.PublishAt, err = parseScheduleTime(strings.TrimSpace(field("publish_at"))); err != nil {
+			message = "Fecha 'publish_at' inválida, usa RFC 3339"
+		} else if row.ExpireAt, err = parseScheduleTime(strings.TrimSpace(field("expire_at"))); err != nil {
+			message = "Fecha 'expire_at' inválida, usa RFC 3339"
+		}
+		rows = append(rows, row)
+		rowErrors = append(rowErrors, message)
+	}
+	return rows, rowErrors, nil
+}
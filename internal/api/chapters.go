@@ -0,0 +1,151 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"streamvault/internal/models"
+	"streamvault/internal/subtitles"
+
+	"github.com/gorilla/mux"
+)
+
+// maxChapters limita la cantidad de capítulos por video.
+const maxChapters = 200
+
+// syncDescriptionChapters vuelve a leer los capítulos de la descripción del video. Si un admin
+// editó los capítulos a mano, se respetan y la descripción se ignora.
+// Los errores solo se registran: no deben hacer fallar la subida o la edición del video.
+func (a *App) syncDescriptionChapters(video *models.Video) {
+	_, source, err := a.Store.GetChapters(video.ID)
+	if err != nil {
+		log.Printf("[Video ID: %d] Error al leer los capítulos: %v", video.ID, err)
+		return
+	}
+	if source == models.ChapterSourceManual {
+		return
+	}
+	var chapters []models.Chapter
+	for _, ch := range subtitles.ParseDescriptionChapters(video.Description) {
+		chapters = append(chapters, models.Chapter{Start: ch.Start.Seconds(), Title: ch.Title})
+	}
+	if err := a.Store.ReplaceChapters(video.ID, chapters, models.ChapterSourceDescription); err != nil {
+		log.Printf("[Video ID: %d] Error al guardar los capítulos: %v", video.ID, err)
+	}
+}
+
+// attachChapters completa los capítulos del video para la respuesta de detalle.
+func (h *handler) attachChapters(video *models.Video) error {
+	chapters, _, err := h.app.Store.GetChapters(video.ID)
+	if err != nil {
+		return err
+	}
+	video.Chapters = chapters
+	return nil
+}
+
+// validateChapters comprueba que los capítulos estén en orden, con un título de una sola línea
+// que se pueda escribir en WebVTT, y que las miniaturas sean URLs http(s) o rutas del propio servidor.
+func validateChapters(chapters []models.Chapter) string {
+	if len(chapters) > maxChapters {
+		return "Demasiados capítulos"
+	}
+	for i, ch := range chapters {
+		switch {
+		case ch.Start < 0:
+			return "Capítulo " + strconv.Itoa(i+1) + ": el inicio no puede ser negativo"
+		case strings.TrimSpace(ch.Title) == "":
+			return "Capítulo " + strconv.Itoa(i+1) + ": falta el título"
+		case utf8.RuneCountInString(ch.Title) > subtitles.MaxChapterTitleLen:
+			return "Capítulo " + strconv.Itoa(i+1) + ": el título no puede superar los " + strconv.Itoa(subtitles.MaxChapterTitleLen) + " caracteres"
+		case strings.ContainsAny(ch.Title, "\r\n") || strings.Contains(ch.Title, "-->"):
+			return "Capítulo " + strconv.Itoa(i+1) + ": el título no puede tener saltos de línea ni '-->'"
+		case i > 0 && ch.Start <= chapters[i-1].Start:
+			return "Capítulo " + strconv.Itoa(i+1) + ": debe empezar después del anterior"
+		case ch.ThumbnailURL != "" && !strings.HasPrefix(ch.ThumbnailURL, "/") &&
+			!strings.HasPrefix(ch.ThumbnailURL, "http://") && !strings.HasPrefix(ch.ThumbnailURL, "https://"):
+			return "Capítulo " + strconv.Itoa(i+1) + ": la miniatura debe ser una URL http(s) o una ruta del servidor"
+		}
+	}
+	return ""
+}
+
+// HandleUpdateChapters reemplaza los capítulos de un video por los enviados, que pasan a ser
+// manuales. Enviar una lista vacía descarta la edición manual y vuelve a usar la descripción.
+func (h *handler) HandleUpdateChapters(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	video, err := h.app.Store.GetVideoByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	if !canEditVideo(r, video) {
+		respondWithError(w, http.StatusForbidden, "Solo puedes editar tus propios videos")
+		return
+	}
+	var chapters []models.Chapter
+	if err := json.NewDecoder(r.Body).Decode(&chapters); err != nil {
+		respondWithError(w, http.StatusBadRequest, "El cuerpo debe ser una lista de capítulos")
+		return
+	}
+	if msg := validateChapters(chapters); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if len(chapters) == 0 {
+		// Se borra la edición manual para que syncDescriptionChapters vuelva a tomar el control.
+		if err := h.app.Store.ReplaceChapters(id, nil, models.ChapterSourceDescription); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error al actualizar los capítulos")
+			return
+		}
+		h.app.syncDescriptionChapters(video)
+	} else if err := h.app.Store.ReplaceChapters(id, chapters, models.ChapterSourceManual); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al actualizar los capítulos")
+		return
+	}
+	if err := h.attachChapters(video); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al obtener los capítulos")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, video.Chapters)
+}
+
+// HandleGetChaptersVTT exporta los capítulos como una pista WebVTT de tipo "chapters",
+// para usarla con <track kind="chapters">.
+func (h *handler) HandleGetChaptersVTT(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	video, err := h.app.Store.GetVideoByID(id)
+	if err != nil || !canSeeVideo(r, video) {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	chapters, _, err := h.app.Store.GetChapters(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al obtener los capítulos")
+		return
+	}
+	if len(chapters) == 0 {
+		respondWithError(w, http.StatusNotFound, "El video no tiene capítulos")
+		return
+	}
+	parsed := make([]subtitles.Chapter, 0, len(chapters))
+	for _, ch := range chapters {
+		parsed = append(parsed, subtitles.Chapter{Start: time.Duration(ch.Start * float64(time.Second)), Title: ch.Title})
+	}
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	duration := time.Duration(video.Media.DurationSeconds * float64(time.Second))
+	w.Write([]byte(subtitles.ChaptersVTT(parsed, duration)))
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"streamvault/internal/models"
+)
+
+// contentHashBatch es la cantidad de videos cuyo hash se calcula en cada ciclo de la tarea.
+const contentHashBatch = 50
+
+// duplicateVideoResponse es la respuesta de una subida rechazada por duplicada. El video
+// existente solo se indica si quien sube puede verlo.
+type duplicateVideoResponse struct {
+	Message       string `json:"message"`
+	ExistingID    int    `json:"existing_video_id,omitempty"`
+	ExistingVideo string `json:"existing_video_url,omitempty"`
+}
+
+// respondDuplicate rechaza una subida cuyo contenido ya existe en el catálogo.
+func respondDuplicate(w http.ResponseWriter, r *http.Request, existing *models.Video) {
+	resp := duplicateVideoResponse{Message: "Este archivo ya fue subido"}
+	if canSeeVideo(r, existing) || canEditVideo(r, existing) {
+		resp.Message = fmt.Sprintf("Este archivo ya fue subido como '%s'", existing.Title)
+		resp.ExistingID = existing.ID
+		resp.ExistingVideo = fmt.Sprintf("/api/videos/%d", existing.ID)
+	}
+	respondWithJSON(w, http.StatusConflict, resp)
+}
+
+// hashFile calcula el SHA-256 de un archivo en hexadecimal.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// backfillContentHashes calcula el hash de los videos que no lo tienen, para que también se
+// detecten duplicados de los archivos subidos antes de la deduplicación. Los videos se recorren
+// por ID: un archivo ilegible se salta en este ciclo y se reintenta en el próximo, sin impedir
+// que se calcule el hash de los que vienen después. De estos videos ya no se tiene el archivo
+// subido, así que el hash es el del archivo guardado.
+func (a *App) backfillContentHashes() error {
+	lastID := 0
+	for {
+		videos, err := a.Store.GetVideosWithoutContentHash(lastID, contentHashBatch)
+		if err != nil {
+			return err
+		}
+		for _, video := range videos {
+			lastID = video.ID
+			hash, err := hashFile(a.videoFilePath(video))
+			if err != nil {
+				log.Printf("[Video ID: %d] No se pudo calcular el hash: %v", video.ID, err)
+				continue
+			}
+			if err := a.Store.SetVideoContentHash(video.ID, hash); err != nil {
+				return err
+			}
+		}
+		if len(videos) < contentHashBatch {
+			return nil
+		}
+	}
+}
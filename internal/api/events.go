@@ -0,0 +1,207 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Tipos de evento del procesamiento de un video. No hay transcodificación, así que todavía no
+// se emite ningún "transcoding-percent". "published" se emite cuando un video programado llega
+// a su fecha de publicación.
+const (
+	eventUploadReceived = "upload-received"
+	eventProbing        = "probing"
+	eventThumbnails     = "thumbnails"
+	eventReady          = "ready"
+	eventFailed         = "failed"
+	eventPublished      = "published"
+)
+
+const (
+	// eventHistorySize es la cantidad de eventos recientes de cada video que se guardan para
+	// reenviarlos a quien se reconecta con Last-Event-ID.
+	eventHistorySize = 50
+	// eventRetention es cuánto se conservan los eventos de un video después del último.
+	eventRetention = time.Hour
+	// eventSubscriberBuffer es cuántos eventos puede tener pendientes un cliente lento antes de
+	// que se lo desconecte; al reconectarse recibe lo que se perdió.
+	eventSubscriberBuffer = 16
+	// eventKeepAlive es cada cuánto se envía un comentario para que los proxies no corten la conexión.
+	eventKeepAlive = 30 * time.Second
+)
+
+// videoEvent es un evento del procesamiento de un video.
+type videoEvent struct {
+	ID      int64
+	VideoID int
+	Type    string
+	Data    map[string]interface{}
+	At      time.Time
+}
+
+// eventBroker reparte los eventos de los videos entre los clientes suscritos, dentro del mismo
+// proceso, y guarda los más recientes de cada video.
+type eventBroker struct {
+	mu          sync.Mutex
+	lastID      int64
+	history     map[int][]videoEvent
+	subscribers map[int]map[chan videoEvent]struct{}
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{
+		// Los IDs parten de la hora de inicio para que un Last-Event-ID de antes de un reinicio
+		// no sea mayor que los IDs nuevos.
+		lastID:      time.Now().UnixMicro(),
+		history:     make(map[int][]videoEvent),
+		subscribers: make(map[int]map[chan videoEvent]struct{}),
+	}
+}
+
+// publish registra un evento y lo envía a los suscritos al video. A un cliente que no da abasto
+// se le cierra el canal en lugar de bloquear a quien publica.
+func (b *eventBroker) publish(videoID int, typ string, data map[string]interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	event := videoEvent{ID: b.lastID, VideoID: videoID, Type: typ, Data: data, At: time.Now()}
+	history := append(b.history[videoID], event)
+	if len(history) > eventHistorySize {
+		history = history[len(history)-eventHistorySize:]
+	}
+	b.history[videoID] = history
+	for ch := range b.subscribers[videoID] {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subscribers[videoID], ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe devuelve los eventos guardados del video posteriores a 'afterID' y un canal con los
+// siguientes. Las dos cosas se obtienen juntas para no perder ni repetir eventos.
+func (b *eventBroker) subscribe(videoID int, afterID int64) ([]videoEvent, chan videoEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var replay []videoEvent
+	for _, event := range b.history[videoID] {
+		if event.ID > afterID {
+			replay = append(replay, event)
+		}
+	}
+	ch := make(chan videoEvent, eventSubscriberBuffer)
+	if b.subscribers[videoID] == nil {
+		b.subscribers[videoID] = make(map[chan videoEvent]struct{})
+	}
+	b.subscribers[videoID][ch] = struct{}{}
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[videoID][ch]; ok {
+			delete(b.subscribers[videoID], ch)
+			close(ch)
+		}
+		if len(b.subscribers[videoID]) == 0 {
+			delete(b.subscribers, videoID)
+		}
+	}
+	return replay, ch, cancel
+}
+
+// prune olvida los eventos de los videos sin actividad desde 'before'.
+func (b *eventBroker) prune(before time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for videoID, history := range b.history {
+		if history[len(history)-1].At.Before(before) {
+			delete(b.history, videoID)
+		}
+	}
+}
+
+// videoEvents devuelve el broker de eventos de la App, creándolo la primera vez.
+func (a *App) videoEvents() *eventBroker {
+	a.eventsOnce.Do(func() { a.events = newEventBroker() })
+	return a.events
+}
+
+// publishVideoEvent emite un evento del procesamiento de un video.
+func (a *App) publishVideoEvent(videoID int, typ string, data map[string]interface{}) {
+	a.videoEvents().publish(videoID, typ, data)
+}
+
+// pruneVideoEvents es la tarea periódica que descarta los eventos viejos.
+func (a *App) pruneVideoEvents() error {
+	a.videoEvents().prune(time.Now().Add(-eventRetention))
+	return nil
+}
+
+// HandleVideoEvents envía como Server-Sent Events el avance del procesamiento de un video.
+// Al conectarse (o reconectarse con Last-Event-ID) se reenvían primero los eventos recientes.
+func (h *handler) HandleVideoEvents(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	if _, err := h.app.Store.GetVideoByID(id); err != nil {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	lastID, _ := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64)
+	replay, events, cancel := h.app.videoEvents().subscribe(id, lastID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	for _, event := range replay {
+		writeSSEEvent(w, event)
+	}
+	if err := rc.Flush(); err != nil {
+		log.Printf("[Video ID: %d] No se pueden enviar eventos: %v", id, err)
+		return
+	}
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				// El broker desconectó a este cliente por lento; al reconectarse recupera lo perdido.
+				return
+			}
+			writeSSEEvent(w, event)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeSSEEvent escribe un evento en el formato de Server-Sent Events. Los datos van en JSON,
+// en una sola línea, junto con el ID del video y la hora del evento.
+func writeSSEEvent(w http.ResponseWriter, event videoEvent) {
+	payload := map[string]interface{}{"video_id": event.VideoID, "at": event.At}
+	for key, value := range event.Data {
+		payload[key] = value
+	}
+	data, _ := json.Marshal(payload)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
@@ -4,8 +4,8 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
@@ -13,6 +13,8 @@ import (
 	"strconv"
 	"streamvault/internal/models"
 	"streamvault/internal/storage"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -28,6 +30,27 @@ type App struct {
 	UploadDir               string
 	JwtSecret               string
 	EnableEmailVerification bool
+	// RoleQuotas es la cuota de subida por defecto de cada rol. Un rol sin entrada no tiene límite.
+	RoleQuotas map[string]models.Quota
+	// TrashRetention es cuánto tiempo queda un video en la papelera antes de borrarse definitivamente.
+	TrashRetention time.Duration
+	// MaxUploadSize es el tamaño máximo en bytes de un video subido.
+	MaxUploadSize int64
+	// QuarantineOrphans hace que la reconciliación periódica mueva los archivos huérfanos de
+	// UPLOAD_DIR a la cuarentena en lugar de solo informarlos.
+	QuarantineOrphans bool
+	// WatchDir es la carpeta vigilada: cada video que se deja en ella se importa solo. Vacío la
+	// desactiva.
+	WatchDir string
+	// WatchInterval es cada cuánto se revisa WatchDir cuando no llegan avisos del sistema.
+	WatchInterval time.Duration
+	// LibraryDir es la carpeta de la que se pueden importar bibliotecas de Kodi/Jellyfin. Vacío
+	// desactiva la importación.
+	LibraryDir string
+
+	publishedListeners []VideoPublishedListener
+	eventsOnce         sync.Once
+	events             *eventBroker
 }
 
 // handler es una estructura que encapsula la aplicación.
@@ -52,18 +75,80 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.Write(response)
 }
 
+// paginatedVideos es el formato de respuesta de los listados paginados de videos.
+type paginatedVideos struct {
+	Videos []*models.Video `json:"videos"`
+	Page   int             `json:"page"`
+	Limit  int             `json:"limit"`
+	Total  int             `json:"total"`
+}
+
+// parsePagination lee los parámetros 'page' y 'limit' de la query string.
+// Los valores ausentes o inválidos se reemplazan por la primera página de 20 elementos,
+// y 'limit' nunca supera 100 para no devolver respuestas enormes.
+func parsePagination(r *http.Request) (page, limit int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	return page, limit
+}
+
+// normalizeTags pasa las etiquetas a minúsculas, quita espacios y elimina vacías y duplicadas.
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
 // --- FUNCIÓN AUXILIAR PARA TAREAS EN SEGUNDO PLANO ---
 
-// processVideoInBackground simula una tarea de larga duración que se ejecuta en segundo plano.
-// Es nuestra demostración del concepto de CONCURRENCIA.
-func processVideoInBackground(videoID int) {
+// processVideoInBackground procesa un video recién subido en su propia goroutine, para que
+// la respuesta de la subida no tenga que esperar. Es nuestra demostración del concepto de CONCURRENCIA.
+func (a *App) processVideoInBackground(video *models.Video) {
 	// Se registra el inicio de la tarea en la consola del servidor.
-	log.Printf("[Video ID: %d] Iniciando procesamiento en segundo plano (ej: generar miniaturas)...", videoID)
-	// time.Sleep simula una tarea que consume tiempo, como podría ser la transcodificación de un video
-	// o la generación de diferentes calidades.
-	time.Sleep(10 * time.Second)
+	log.Printf("[Video ID: %d] Iniciando procesamiento en segundo plano...", video.ID)
+	// El video se toma antes de analizarlo: si la tarea de análisis pendiente ya lo tomó, es ella
+	// la que lo analiza. Si falla, queda pendiente y lo analiza esa tarea en su próximo ciclo.
+	claimed, err := a.Store.ClaimVideoProbe(video.ID)
+	if err != nil {
+		log.Printf("[Video ID: %d] Error al tomar el video para analizarlo: %v", video.ID, err)
+		return
+	}
+	if !claimed {
+		log.Printf("[Video ID: %d] El archivo ya lo está analizando otra tarea", video.ID)
+		return
+	}
+	// Analiza el archivo para conocer su duración, resolución y códecs.
+	a.publishVideoEvent(video.ID, eventProbing, nil)
+	if err := a.probeVideo(video); err != nil {
+		log.Printf("[Video ID: %d] Error al guardar el análisis del archivo: %v", video.ID, err)
+		a.publishVideoEvent(video.ID, eventFailed, map[string]interface{}{"error": "No se pudo guardar el análisis del archivo"})
+	} else if video.Media.Status == models.MediaStatusFailed {
+		a.publishVideoEvent(video.ID, eventFailed, map[string]interface{}{"error": video.Media.Error})
+	} else {
+		// Reordena los MP4 con el índice al final. El hash guardado sigue siendo el del archivo
+		// tal como se subió, que es con el que se comparan las subidas siguientes.
+		a.fastStartVideo(video)
+		a.publishVideoEvent(video.ID, eventReady, map[string]interface{}{"media": video.Media})
+	}
 	// Se registra la finalización de la tarea.
-	log.Printf("[Video ID: %d] ...Procesamiento en segundo plano finalizado.", videoID)
+	log.Printf("[Video ID: %d] ...Procesamiento en segundo plano finalizado.", video.ID)
 }
 
 // --- HANDLERS DE AUTENTICACIÓN Y USUARIOS ---
@@ -112,6 +197,7 @@ func (h *handler) HandleLoginUser(w http.ResponseWriter, r *http.Request) {
 	}
 	// Si las credenciales son correctas, crea las "claims" para el token JWT.
 	claims := &models.Claims{
+		UserID:   user.ID,
 		Username: user.Username,
 		Email:    user.Email,
 		Role:     user.Role,
@@ -132,14 +218,20 @@ func (h *handler) HandleLoginUser(w http.ResponseWriter, r *http.Request) {
 
 // --- HANDLERS PÚBLICOS DE VIDEOS ---
 
-// HandleListVideos obtiene y devuelve la lista de todos los videos.
+// HandleListVideos obtiene y devuelve la lista de todos los videos visibles para quien consulta.
 func (h *handler) HandleListVideos(w http.ResponseWriter, r *http.Request) {
 	videos, err := h.app.Store.GetAllVideos()
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "No se pudieron obtener los videos")
 		return
 	}
-	respondWithJSON(w, http.StatusOK, videos)
+	visible := []*models.Video{}
+	for _, video := range videos {
+		if canSeeVideo(r, video) {
+			visible = append(visible, video)
+		}
+	}
+	respondWithJSON(w, http.StatusOK, visible)
 }
 
 // HandleGetVideoByID obtiene los detalles de un solo video por su ID.
@@ -151,10 +243,20 @@ func (h *handler) HandleGetVideoByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	video, err := h.app.Store.GetVideoByID(id)
-	if err != nil {
+	// Un video programado o vencido se trata como inexistente para quien no puede verlo.
+	if err != nil || !canSeeVideo(r, video) {
 		respondWithError(w, http.StatusNotFound, "Video no encontrado")
 		return
 	}
+	if err := h.attachSubtitles(video); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "No se pudieron obtener los subtítulos")
+		return
+	}
+	if err := h.attachChapters(video); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "No se pudieron obtener los capítulos")
+		return
+	}
+	setVideoETag(w, video)
 	respondWithJSON(w, http.StatusOK, video)
 }
 
@@ -162,7 +264,23 @@ func (h *handler) HandleGetVideoByID(w http.ResponseWriter, r *http.Request) {
 func (h *handler) HandleStreamVideo(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	fileName := vars["filename"]
-	videoPath := filepath.Join(h.app.UploadDir, fileName)
+	// Solo se sirven archivos con un video registrado y visible: así tampoco se exponen los
+	// videos de la papelera ni otros archivos de UPLOAD_DIR (como las portadas).
+	video, err := h.app.Store.GetVideoByFilePath(fileName)
+	if err != nil || !canSeeVideo(r, video) {
+		http.NotFound(w, r)
+		return
+	}
+	videoPath := h.app.videoFilePath(video)
+	// El tipo se toma del contenido detectado al subir el archivo. ServeFile respeta un Content-Type
+	// ya puesto; si el video aún no se analizó, lo deduce de la extensión como antes.
+	if video.MimeType != "" {
+		w.Header().Set("Content-Type", video.MimeType)
+	}
+	// La vista se registra al empezar: ServeFile no vuelve hasta que termina la transferencia, que
+	// en una petición "bytes=0-" dura toda la reproducción. Las vistas repetidas (como cada
+	// petición Range) se deduplican en la capa de datos.
+	h.recordStreamView(r, video)
 	// http.ServeFile es una función de Go que se encarga de servir un archivo.
 	// Soporta 'Range requests', crucial para que los navegadores puedan buscar (seek) en el video.
 	http.ServeFile(w, r, videoPath)
@@ -170,52 +288,212 @@ func (h *handler) HandleStreamVideo(w http.ResponseWriter, r *http.Request) {
 
 // --- HANDLERS DE ADMINISTRACIÓN (PROTEGIDOS) ---
 
-// HandleUploadVideo maneja la subida de un archivo de video.
+// HandleUploadVideo maneja la subida de un archivo de video. La usan tanto los admins como
+// los usuarios normales; a todos se les aplica su cuota y el video queda a nombre de quien lo sube.
 func (h *handler) HandleUploadVideo(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseMultipartForm(50 << 20); err != nil {
-		respondWithError(w, http.StatusBadRequest, "El archivo es demasiado grande (límite 50MB)")
+	claims, ok := claimsFromContext(r)
+	if !ok || claims.UserID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "Sesión inválida, vuelve a iniciar sesión")
 		return
 	}
-	title := r.FormValue("title")
-	category := r.FormValue("category")
+	h.receiveUpload(w, r, claims, uploadLimits{maxSize: h.app.maxUploadSize()})
+}
+
+// receiveUpload lee un formulario de subida y crea el video a nombre de 'claims', respetando
+// 'limits'. Devuelve true si el video se creó; si no, ya respondió con el error.
+func (h *handler) receiveUpload(w http.ResponseWriter, r *http.Request, claims *models.Claims, limits uploadLimits) bool {
+	// Se rechaza cuanto antes lo que ya se sabe que no entra: la cuota de videos, o los bytes
+	// si el Content-Length indica que el archivo no cabe en lo que le queda al usuario.
+	if !h.checkQuotaOrRespond(w, claims, max(r.ContentLength-uploadFormOverhead, 0)) {
+		return false
+	}
+	if r.ContentLength > limits.maxSize+uploadFormOverhead {
+		status, msg := limits.tooLargeError()
+		respondWithError(w, status, msg)
+		return false
+	}
+	// El formulario se lee como un stream: el video va directo a disco y la memoria usada no
+	// depende de su tamaño. MaxBytesReader corta la petición si supera el límite.
+	r.Body = http.MaxBytesReader(w, r.Body, limits.maxSize+uploadFormOverhead)
+	form, status, msg := h.readUploadForm(r, limits)
+	if form == nil {
+		respondWithError(w, status, msg)
+		return false
+	}
+	field := func(name string) string { return form.fields[name] }
+	video, msg := videoFromUploadFields(field)
+	if msg != "" {
+		form.discard(h.app.UploadDir)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return false
+	}
+	video.MimeType = form.mimeType
+	// Con on_duplicate=link un admin puede crear el video aunque el archivo ya exista:
+	// los metadatos nuevos quedan asociados al mismo archivo en lugar de guardar otra copia.
+	linkDuplicate := field("on_duplicate") == "link"
+	if linkDuplicate && !isAdmin(r) {
+		form.discard(h.app.UploadDir)
+		respondWithError(w, http.StatusForbidden, "Solo un admin puede asociar un video a un archivo existente")
+		return false
+	}
+	// Ahora que se conoce el tamaño real se vuelve a comprobar la cuota.
+	if !h.checkQuotaOrRespond(w, claims, form.size) {
+		form.discard(h.app.UploadDir)
+		return false
+	}
+	if !h.registerUploadedVideo(w, r, claims, video, form.fileName, form.size, form.hash, linkDuplicate) {
+		return false
+	}
+	if form.poster != nil {
+		// Si falla, el video ya está guardado y la portada se puede volver a subir después.
+		if err := h.app.savePoster(video.ID, form.poster); err != nil {
+			log.Printf("[Video ID: %d] Error al guardar la portada: %v", video.ID, err)
+		} else if updated, err := h.app.Store.GetVideoByID(video.ID); err == nil {
+			video.Poster = updated.Poster
+		}
+	}
+	respondWithJSON(w, http.StatusCreated, video)
+	return true
+}
+
+// uniqueFileName arma el nombre con el que se guarda un archivo subido: el nombre original
+// (sin directorios) con un prefijo que evita colisiones.
+func uniqueFileName(original string) string {
+	return fmt.Sprintf("%d_%s", time.Now().UnixNano(), filepath.Base(original))
+}
+
+// videoFilePath es la ruta en disco del archivo de un video: la de la biblioteca si se importó
+// sin copiarlo, o la de UPLOAD_DIR en los demás casos.
+func (a *App) videoFilePath(video *models.Video) string {
+	if video.SourcePath != "" {
+		return video.SourcePath
+	}
+	return filepath.Join(a.UploadDir, video.FilePath)
+}
+
+// videoFromUploadFields arma el video a partir de los metadatos de una subida. 'field' devuelve
+// el valor de cada campo (del formulario o de los metadatos de una subida reanudable).
+// Si algún campo es inválido devuelve el mensaje de error para el cliente.
+func videoFromUploadFields(field func(string) string) (*models.Video, string) {
+	title := field("title")
+	category := field("category")
 	if title == "" || category == "" {
-		respondWithError(w, http.StatusBadRequest, "Faltan los campos 'title' o 'category'")
-		return
+		return nil, "Faltan los campos 'title' o 'category'"
 	}
-	file, fileHandler, err := r.FormFile("video")
+	// Las fechas de publicación y vencimiento son opcionales y usan formato RFC 3339.
+	publishAt, err := parseScheduleTime(field("publish_at"))
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Petición inválida: Falta el archivo con la clave 'video'")
-		return
+		return nil, "Fecha 'publish_at' inválida, usa RFC 3339"
 	}
-	defer file.Close()
-	// Genera un nombre de archivo único para evitar colisiones.
-	fileName := fmt.Sprintf("%d_%s", time.Now().UnixNano(), filepath.Base(fileHandler.Filename))
-	filePath := filepath.Join(h.app.UploadDir, fileName)
-	dst, err := os.Create(filePath)
+	expireAt, err := parseScheduleTime(field("expire_at"))
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error interno al guardar el archivo")
-		return
-	}
-	defer dst.Close()
-	if _, err := io.Copy(dst, file); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error interno al procesar el archivo")
-		return
+		return nil, "Fecha 'expire_at' inválida, usa RFC 3339"
 	}
+	// Las etiquetas llegan separadas por comas (ej: "go,tutorial").
+	tags := normalizeTags(strings.Split(field("tags"), ","))
 	video := &models.Video{
 		Title:       title,
-		Description: r.FormValue("description"),
+		Description: field("description"),
 		Category:    category,
-		FilePath:    fileName,
+		Tags:        tags,
+		PublishAt:   publishAt,
+		ExpireAt:    expireAt,
 	}
-	// Guarda los metadatos en la base de datos a través de la interfaz.
-	if err := h.app.Store.CreateVideo(video); err != nil {
-		os.Remove(filePath) // Limpia el archivo si la BD falla.
+	if err := validateSchedule(video); err != nil {
+		return nil, err.Error()
+	}
+	return video, ""
+}
+
+// registerUploadedVideo crea el registro de un video subido por HTTP (ver addUploadedVideo) a
+// nombre de quien hace la petición, dentro de su cuota. Devuelve false si ya respondió con un error.
+func (h *handler) registerUploadedVideo(w http.ResponseWriter, r *http.Request, claims *models.Claims, video *models.Video,
+	fileName string, size int64, hash string, linkDuplicate bool) bool {
+	quota, err := h.app.effectiveQuota(claims.UserID, claims.Role)
+	if err != nil {
+		os.Remove(filepath.Join(h.app.UploadDir, fileName))
+		respondWithError(w, http.StatusInternalServerError, "Error al verificar la cuota")
+		return false
+	}
+	duplicate, err := h.app.addUploadedVideo(video, fileName, size, hash, claims.UserID, &quota, linkDuplicate)
+	if errors.Is(err, storage.ErrQuotaExceeded) {
+		// Otra subida del mismo usuario terminó antes; el mensaje detallado refleja el consumo actual.
+		exceeded, _ := h.checkUploadQuota(claims, size)
+		if exceeded == "" {
+			exceeded = "Cuota excedida"
+		}
+		respondWithError(w, http.StatusForbidden, exceeded)
+		return false
+	}
+	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error al guardar la información del video")
-		return
+		return false
 	}
-	// Inicia una tarea en segundo plano (goroutine) para "procesar" el video.
-	go processVideoInBackground(video.ID)
-	respondWithJSON(w, http.StatusCreated, video)
+	if duplicate != nil {
+		respondDuplicate(w, r, duplicate)
+		return false
+	}
+	return true
+}
+
+// addUploadedVideo crea el registro de un video cuyo archivo ya está en UPLOAD_DIR con el nombre
+// 'fileName', a nombre de 'uploaderID' (0 si no lo subió ningún usuario). Si 'quota' no es nil,
+// el video solo se crea si entra en ella (si no, devuelve storage.ErrQuotaExceeded). Si el contenido ya
+// existe, borra el archivo y devuelve el video existente sin crear nada, salvo que 'linkDuplicate'
+// pida asociar el video al archivo existente. Si falla, el archivo también se borra.
+// Si el video referencia un archivo de una biblioteca (video.SourcePath), 'fileName' es solo el
+// nombre con el que se sirve y el archivo nunca se borra.
+func (a *App) addUploadedVideo(video *models.Video, fileName string, size int64, hash string,
+	uploaderID int, quota *models.Quota, linkDuplicate bool) (*models.Video, error) {
+	filePath := ""
+	if video.SourcePath == "" {
+		filePath = filepath.Join(a.UploadDir, fileName)
+	}
+	discard := func() {
+		if filePath != "" {
+			os.Remove(filePath)
+		}
+	}
+	video.ContentHash = hash
+	existing, err := a.Store.FindVideoByContentHash(hash)
+	if err != nil {
+		discard()
+		return nil, err
+	}
+	if existing != nil {
+		discard()
+		if !linkDuplicate {
+			return existing, nil
+		}
+		fileName, filePath, size = existing.FilePath, "", existing.SizeBytes
+		video.SourcePath = existing.SourcePath
+	}
+	video.FilePath = fileName
+	video.SizeBytes = size
+	if uploaderID != 0 {
+		video.UploaderID = &uploaderID
+	}
+	video.Media.Status = models.MediaStatusPending
+	// Guarda los metadatos en la base de datos a través de la interfaz.
+	create := a.Store.CreateVideo
+	if quota != nil {
+		create = func(v *models.Video) error { return a.Store.CreateVideoWithinQuota(v, *quota) }
+	}
+	if err := create(video); err != nil {
+		discard() // Limpia el archivo si la BD falla (salvo que sea el de otro video).
+		return nil, err
+	}
+	a.publishVideoEvent(video.ID, eventUploadReceived, map[string]interface{}{
+		"file_path": video.FilePath, "size_bytes": video.SizeBytes, "mime_type": video.MimeType,
+	})
+	// La primera revisión del historial es el estado con el que se subió el video.
+	a.recordRevision(nil, video, uploaderID)
+	a.syncDescriptionChapters(video)
+	// Inicia una tarea en segundo plano (goroutine) para "procesar" el video. Recibe una copia
+	// para no modificar el video mientras se serializa la respuesta.
+	processing := *video
+	go a.processVideoInBackground(&processing)
+	return nil, nil
 }
 
 // HandleUpdateVideo actualiza los detalles de un video existente.
@@ -226,20 +504,86 @@ func (h *handler) HandleUpdateVideo(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
 		return
 	}
-	var updatedVideo models.Video
+	existing, err := h.app.Store.GetVideoByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	if !canEditVideo(r, existing) {
+		respondWithError(w, http.StatusForbidden, "Solo puedes editar tus propios videos")
+		return
+	}
+	// Igual que en PATCH, If-Match es obligatorio para no pisar en silencio una edición concurrente.
+	if r.Header.Get("If-Match") == "" {
+		respondWithError(w, http.StatusPreconditionRequired, "Falta el encabezado If-Match con el ETag del video")
+		return
+	}
+	expected, ok := parseIfMatch(r.Header.Get("If-Match"))
+	if !ok {
+		respondWithError(w, http.StatusPreconditionFailed, "El encabezado If-Match no es válido")
+		return
+	}
+	// Las etiquetas, la ventana de publicación y el año de estreno se conservan si el cuerpo no
+	// los trae: un cliente que solo envía título, descripción y categoría no debe publicar un
+	// video programado. Para quitarlos hay que enviarlos explícitamente vacíos o en null.
+	updatedVideo := models.Video{Tags: existing.Tags, PublishAt: existing.PublishAt, ExpireAt: existing.ExpireAt,
+		ReleaseYear: existing.ReleaseYear}
 	if err := json.NewDecoder(r.Body).Decode(&updatedVideo); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Request inválido")
 		return
 	}
-	updatedVideo.ID = id
-	if err := h.app.Store.UpdateVideo(&updatedVideo); err != nil {
+	h.saveVideoEdit(w, r, existing, &updatedVideo, expected)
+}
+
+// saveVideoEdit guarda los metadatos editados de 'existing' y responde con el video actualizado.
+// 'expectedVersion' es la versión que el cliente dice estar editando (0 para no comprobarla).
+func (h *handler) saveVideoEdit(w http.ResponseWriter, r *http.Request, existing, updatedVideo *models.Video, expectedVersion int) {
+	updatedVideo.ID = existing.ID
+	// Los campos que no se editan por esta vía se conservan para que la respuesta esté completa.
+	updatedVideo.FilePath = existing.FilePath
+	updatedVideo.SizeBytes = existing.SizeBytes
+	updatedVideo.UploadedAt = existing.UploadedAt
+	updatedVideo.UploaderID = existing.UploaderID
+	updatedVideo.ExternalID = existing.ExternalID
+	updatedVideo.Poster = existing.Poster
+	updatedVideo.Media = existing.Media
+	updatedVideo.ContentHash = existing.ContentHash
+	updatedVideo.MimeType = existing.MimeType
+	updatedVideo.SourcePath = existing.SourcePath
+	updatedVideo.Version = expectedVersion
+	updatedVideo.Tags = normalizeTags(updatedVideo.Tags)
+	if updatedVideo.Title == "" || updatedVideo.Category == "" {
+		respondWithError(w, http.StatusBadRequest, "Los campos 'title' y 'category' no pueden quedar vacíos")
+		return
+	}
+	if err := validateSchedule(updatedVideo); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if year := updatedVideo.ReleaseYear; year != nil && !validReleaseYear(*year) {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("'release_year' debe estar entre %d y %d", minReleaseYear, maxReleaseYear))
+		return
+	}
+	claims, _ := claimsFromContext(r)
+	if err := h.app.Store.UpdateVideo(updatedVideo, claims.UserID); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			respondWithError(w, http.StatusPreconditionFailed, "El video fue modificado por otra persona. Recárgalo y vuelve a intentarlo.")
+			return
+		}
+		if errors.Is(err, storage.ErrVideoNotFound) {
+			respondWithError(w, http.StatusNotFound, "Video no encontrado")
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, "Error al actualizar el video")
 		return
 	}
+	h.app.syncDescriptionChapters(updatedVideo)
+	setVideoETag(w, updatedVideo)
 	respondWithJSON(w, http.StatusOK, updatedVideo)
 }
 
-// HandleDeleteVideo elimina un video de la base de datos y del sistema de archivos.
+// HandleDeleteVideo mueve un video a la papelera. El archivo se borra en la purga periódica,
+// así que un admin puede restaurarlo mientras tanto.
 func (h *handler) HandleDeleteVideo(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -247,21 +591,20 @@ func (h *handler) HandleDeleteVideo(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
 		return
 	}
-	// Obtiene los datos del video ANTES de borrarlo de la BD para saber el nombre del archivo.
 	video, err := h.app.Store.GetVideoByID(id)
 	if err != nil {
 		respondWithError(w, http.StatusNotFound, "Video no encontrado")
 		return
 	}
-	// Elimina el registro de la BD.
-	if err := h.app.Store.DeleteVideo(id); err != nil {
+	if !canEditVideo(r, video) {
+		respondWithError(w, http.StatusForbidden, "Solo puedes eliminar tus propios videos")
+		return
+	}
+	if err := h.app.Store.TrashVideo(id); err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error al eliminar el video")
 		return
 	}
-	// Si la eliminación de la BD fue exitosa, elimina el archivo físico.
-	filePath := filepath.Join(h.app.UploadDir, video.FilePath)
-	os.Remove(filePath)
-	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Video eliminado exitosamente"})
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Video movido a la papelera"})
 }
 
 // HandleListAllUsers devuelve una lista de todos los usuarios registrados (solo para admins).
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"log"
+	"time"
+)
+
+// StartBackgroundJobs lanza las tareas periódicas de la aplicación, cada una en su propia goroutine.
+func (a *App) StartBackgroundJobs() {
+	a.OnVideoPublished(a.publishPublicationEvent)
+	go a.runEvery(time.Hour, "agregado de estadísticas", a.rollupVideoStats)
+	go a.runEvery(time.Hour, "cálculo de videos relacionados", a.refreshRelatedVideos)
+	go a.runEvery(time.Minute, "publicación programada", a.announceDuePublications)
+	go a.runEvery(time.Hour, "purga de la papelera", a.purgeTrash)
+	go a.runEvery(time.Hour, "vencimiento de subidas reanudables", a.expireUploads)
+	go a.runEvery(time.Hour, "limpieza de enlaces de subida usados", a.purgeUsedUploadURLs)
+	go a.runEvery(time.Hour, "limpieza de eventos de videos", a.pruneVideoEvents)
+	go a.runEvery(24*time.Hour, "reconciliación de archivos", a.reconcileUploadDir)
+	go a.runEvery(time.Hour, "cálculo de hashes pendientes", a.backfillContentHashes)
+	go a.runEvery(time.Hour, "análisis de archivos pendientes", a.probePendingVideos)
+	go a.runOnce("indexado de subtítulos existentes", a.indexExistingSubtitles)
+	if a.WatchDir != "" {
+		go a.watchFolder()
+	}
+}
+
+// runOnce ejecuta una tarea de puesta al día una sola vez, registrando el error si lo hay.
+func (a *App) runOnce(name string, task func() error) {
+	if err := task(); err != nil {
+		log.Printf("Error en la tarea de %s: %v", name, err)
+	}
+}
+
+// runEvery ejecuta la tarea de inmediato y luego en cada intervalo. Un error se registra en el log
+// y no detiene la tarea: el siguiente ciclo vuelve a intentarlo.
+func (a *App) runEvery(interval time.Duration, name string, task func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := task(); err != nil {
+			log.Printf("Error en la tarea de %s: %v", name, err)
+		}
+		<-ticker.C
+	}
+}
+
+// rollupVideoStats recalcula los agregados de las últimas 48 horas. El margen cubre
+// las sesiones que siguieron acumulando tiempo de reproducción después del último ciclo
+// y un eventual ciclo perdido por un reinicio.
+func (a *App) rollupVideoStats() error {
+	return a.Store.RollupVideoStats(time.Now().Add(-48 * time.Hour))
+}
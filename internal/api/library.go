@@ -0,0 +1,373 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"streamvault/internal/media"
+	"streamvault/internal/models"
+	"streamvault/internal/nfo"
+)
+
+const (
+	// maxNFOSize limita el tamaño de cada NFO que se lee.
+	maxNFOSize = 1 << 20
+	// defaultLibraryCategory es la categoría de las películas (y de los episodios sin serie) si la
+	// importación no indica otra.
+	defaultLibraryCategory = "Películas"
+	// Rango admitido para el año de estreno de un video.
+	minReleaseYear = 1888
+	maxReleaseYear = 9999
+)
+
+// validReleaseYear indica si 'year' puede ser el año de estreno de un video.
+func validReleaseYear(year int) bool {
+	return year >= minReleaseYear && year <= maxReleaseYear
+}
+
+// libraryVideoExtensions son las extensiones de los archivos que se consideran el video de un
+// NFO. Luego se comprueba el contenido: los formatos no admitidos (como AVI) se informan como error.
+var libraryVideoExtensions = []string{".mp4", ".m4v", ".mov", ".mkv", ".webm", ".ts", ".m2ts", ".mts",
+	".ogv", ".ogg", ".avi", ".wmv", ".mpg", ".mpeg"}
+
+// libraryImportRequest es el cuerpo de HandleImportLibrary.
+type libraryImportRequest struct {
+	// Path es la carpeta a importar, relativa a LIBRARY_DIR (vacía para importarla entera).
+	Path string `json:"path"`
+	// Mode es "copy" (por defecto) o "reference".
+	Mode string `json:"mode"`
+	// Category es la categoría de las películas; los episodios usan el título de su serie.
+	Category string `json:"category"`
+}
+
+// HandleImportLibrary importa una biblioteca con el formato de Kodi/Jellyfin (solo para admins):
+// recorre la carpeta y crea un video por cada NFO de película o episodio, con su portada si la
+// tiene. En el modo "copy" los archivos se copian a UPLOAD_DIR; en el modo "reference" se usan
+// donde están y StreamVault nunca los modifica ni los borra. Con ?dry_run=true solo se informa
+// qué se importaría; la simulación también calcula el hash de cada archivo, así que informa los
+// duplicados igual que la importación real. Cada NFO se importa por separado y los archivos que
+// ya están en el catálogo se omiten, así que se puede repetir la importación después de agregar
+// contenido.
+func (h *handler) HandleImportLibrary(w http.ResponseWriter, r *http.Request) {
+	if h.app.LibraryDir == "" {
+		respondWithError(w, http.StatusServiceUnavailable, "La importación de bibliotecas está desactivada: configura LIBRARY_DIR")
+		return
+	}
+	var req libraryImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Request inválido")
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = models.LibraryModeCopy
+	}
+	if req.Mode != models.LibraryModeCopy && req.Mode != models.LibraryModeReference {
+		respondWithError(w, http.StatusBadRequest, "Modo inválido. Debe ser 'copy' o 'reference'.")
+		return
+	}
+	req.Category = strings.TrimSpace(req.Category)
+	if req.Category == "" {
+		req.Category = defaultLibraryCategory
+	}
+	// La ruta se limpia como si fuera absoluta para que no pueda salir de LIBRARY_DIR con "..".
+	root, err := filepath.Abs(filepath.Join(h.app.LibraryDir, filepath.Clean("/"+req.Path)))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Ruta inválida")
+		return
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("La carpeta '%s' no existe en LIBRARY_DIR", req.Path))
+		return
+	}
+
+	claims, _ := claimsFromContext(r)
+	report, err := h.app.importLibrary(root, req, r.URL.Query().Get("dry_run") == "true", claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al recorrer la biblioteca")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// importLibrary recorre 'root' e importa cada NFO de película o episodio. Los tvshow.nfo no
+// crean videos: dan el título de la serie, que es la categoría de sus episodios.
+func (a *App) importLibrary(root string, req libraryImportRequest, dryRun bool, uploaderID int) (*models.LibraryImportReport, error) {
+	report := &models.LibraryImportReport{DryRun: dryRun, Mode: req.Mode, Results: []models.LibraryImportItem{}}
+	// Primero se juntan todos los NFO: el tvshow.nfo de una serie puede aparecer después que las
+	// carpetas de sus temporadas.
+	shows := map[string]string{}
+	var nfos []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() && path != root {
+				report.Results = append(report.Results, libraryError(root, path, err))
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !strings.EqualFold(filepath.Ext(d.Name()), ".nfo") {
+			return nil
+		}
+		switch strings.ToLower(d.Name()) {
+		case "tvshow.nfo":
+			info, err := readNFO(path)
+			if err == nil && info.Kind != nfo.KindTVShow {
+				err = errors.New("tvshow.nfo no describe una serie")
+			}
+			if err != nil {
+				report.Results = append(report.Results, libraryError(root, path, err))
+			} else if info.Title != "" {
+				shows[filepath.Dir(path)] = info.Title
+			}
+		case "season.nfo":
+			// Las temporadas no tienen datos que se usen.
+		default:
+			nfos = append(nfos, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range nfos {
+		report.Results = append(report.Results, a.importLibraryNFO(root, path, req, shows, dryRun, uploaderID))
+	}
+	return report, nil
+}
+
+// importLibraryNFO importa el video de un NFO y devuelve el resultado.
+func (a *App) importLibraryNFO(root, path string, req libraryImportRequest, shows map[string]string,
+	dryRun bool, uploaderID int) models.LibraryImportItem {
+	info, err := readNFO(path)
+	if err == nil && info.Kind == nfo.KindTVShow {
+		err = errors.New("es el NFO de una serie; debe llamarse tvshow.nfo")
+	}
+	if err != nil {
+		return libraryError(root, path, err)
+	}
+	videoPath, err := nfoVideoFile(path)
+	if err != nil {
+		return libraryError(root, path, err)
+	}
+	item := models.LibraryImportItem{
+		NFO:      relativeTo(root, path),
+		FilePath: relativeTo(root, videoPath),
+		Status:   models.ImportStatusError,
+	}
+
+	item.Title = info.Title
+	if item.Title == "" {
+		item.Title = strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+	}
+	item.Category = req.Category
+	if info.Kind == nfo.KindEpisode {
+		if info.Season > 0 && info.Episode > 0 {
+			item.Title = fmt.Sprintf("S%02dE%02d - %s", info.Season, info.Episode, item.Title)
+		}
+		if show := showTitle(root, filepath.Dir(path), shows); show != "" {
+			item.Category = show
+		} else if info.ShowTitle != "" {
+			item.Category = info.ShowTitle
+		}
+	}
+	fields := map[string]string{
+		"title": item.Title, "description": info.Plot, "category": item.Category,
+		"tags": strings.Join(info.Genres, ","),
+	}
+	video, msg := videoFromUploadFields(func(key string) string { return fields[key] })
+	if msg != "" {
+		item.Message = msg
+		return item
+	}
+	if validReleaseYear(info.Year) {
+		item.Year = info.Year
+		video.ReleaseYear = &info.Year
+	}
+	// La duración del NFO se usa hasta que se analiza el archivo; el análisis la reemplaza si la obtiene.
+	if info.Runtime > 0 {
+		item.RuntimeMinutes = info.Runtime
+		video.Media.DurationSeconds = float64(info.Runtime * 60)
+	}
+	if video.MimeType, err = media.SniffFile(videoPath); err != nil {
+		item.Message = err.Error()
+		return item
+	}
+	poster := nfoPoster(videoPath, info.Kind)
+	if poster != "" {
+		item.Poster = relativeTo(root, poster)
+	}
+	if dryRun {
+		hash, err := hashFile(videoPath)
+		if err != nil {
+			item.Message = "no se pudo leer el archivo: " + err.Error()
+			return item
+		}
+		duplicate, err := a.Store.FindVideoByContentHash(hash)
+		if err != nil {
+			item.Message = "error al buscar el archivo en el catálogo"
+			return item
+		}
+		if duplicate != nil {
+			return duplicateLibraryItem(item, duplicate)
+		}
+		item.Status = models.ImportStatusCreated
+		return item
+	}
+
+	var fileName, hash string
+	var size int64
+	if req.Mode == models.LibraryModeReference {
+		fileName = uniqueFileName(videoPath)
+		video.SourcePath = videoPath
+		if stat, err := os.Stat(videoPath); err == nil {
+			size = stat.Size()
+		}
+		hash, err = hashFile(videoPath)
+	} else {
+		fileName, size, hash, err = a.copyToUploadDir(videoPath)
+	}
+	if err != nil {
+		item.Message = "no se pudo leer el archivo: " + err.Error()
+		return item
+	}
+	duplicate, err := a.addUploadedVideo(video, fileName, size, hash, uploaderID, nil, false)
+	if err != nil {
+		item.Message = "error al guardar el video"
+		return item
+	}
+	if duplicate != nil {
+		return duplicateLibraryItem(item, duplicate)
+	}
+	item.Status = models.ImportStatusCreated
+	item.VideoID = video.ID
+	log.Printf("[Video ID: %d] Importado de la biblioteca: %s", video.ID, item.NFO)
+	if poster != "" {
+		if err := a.attachLibraryPoster(video.ID, poster); err != nil {
+			item.Message = "el video se creó, pero la portada no se pudo usar: " + err.Error()
+		}
+	}
+	return item
+}
+
+// attachLibraryPoster usa una imagen de la biblioteca como portada del video.
+func (a *App) attachLibraryPoster(videoID int, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	img, _, err := readPoster(f)
+	if err != nil {
+		return err
+	}
+	return a.savePoster(videoID, img)
+}
+
+// readNFO lee y analiza un archivo NFO.
+func readNFO(path string) (*nfo.Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return nfo.Parse(io.LimitReader(f, maxNFOSize))
+}
+
+// nfoVideoFile busca el video de un NFO: el archivo con el mismo nombre y una extensión de video
+// o, para un movie.nfo, el único video de su carpeta.
+func nfoVideoFile(nfoPath string) (string, error) {
+	dir := filepath.Dir(nfoPath)
+	base := strings.TrimSuffix(filepath.Base(nfoPath), filepath.Ext(nfoPath))
+	wholeDir := strings.EqualFold(filepath.Base(nfoPath), "movie.nfo")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var candidates []string
+	for _, entry := range entries {
+		name := entry.Name()
+		ext := filepath.Ext(name)
+		if !entry.Type().IsRegular() || !slices.Contains(libraryVideoExtensions, strings.ToLower(ext)) {
+			continue
+		}
+		if wholeDir || strings.TrimSuffix(name, ext) == base {
+			candidates = append(candidates, filepath.Join(dir, name))
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return "", errors.New("no se encontró el archivo de video del NFO")
+	case 1:
+		return candidates[0], nil
+	}
+	return "", fmt.Errorf("el NFO corresponde a %d archivos de video; no se sabe cuál importar", len(candidates))
+}
+
+// nfoPoster busca la portada de un video con los nombres que usan Kodi y Jellyfin: primero la
+// del propio archivo y, en las películas, la de la carpeta. Devuelve "" si no hay ninguna.
+func nfoPoster(videoPath, kind string) string {
+	dir := filepath.Dir(videoPath)
+	base := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+	names := []string{base + "-poster.jpg", base + "-poster.png", base + "-thumb.jpg", base + "-thumb.png"}
+	// En una serie, el poster.jpg de la carpeta es el de la temporada o la serie, no el del episodio.
+	if kind == nfo.KindMovie {
+		names = append(names, "poster.jpg", "poster.png", "folder.jpg")
+	}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+	}
+	return ""
+}
+
+// showTitle busca el título de la serie en el tvshow.nfo de la carpeta del episodio o de alguna
+// de las superiores, sin salir de la carpeta importada.
+func showTitle(root, dir string, shows map[string]string) string {
+	for {
+		if title, ok := shows[dir]; ok {
+			return title
+		}
+		if dir == root || dir == filepath.Dir(dir) {
+			return ""
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// duplicateLibraryItem completa el resultado de un NFO cuyo archivo ya está en el catálogo.
+func duplicateLibraryItem(item models.LibraryImportItem, duplicate *models.Video) models.LibraryImportItem {
+	item.Status = models.ImportStatusUnchanged
+	item.VideoID = duplicate.ID
+	item.Message = fmt.Sprintf("el archivo ya está en el catálogo como el video %d", duplicate.ID)
+	return item
+}
+
+// libraryError arma el resultado de un NFO o una carpeta que no se pudo importar.
+func libraryError(root, path string, err error) models.LibraryImportItem {
+	return models.LibraryImportItem{NFO: relativeTo(root, path), Status: models.ImportStatusError, Message: err.Error()}
+}
+
+// relativeTo devuelve 'path' relativo a 'root', para no exponer rutas del servidor en el informe.
+func relativeTo(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil {
+		return rel
+	}
+	return filepath.Base(path)
+}
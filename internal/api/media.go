@@ -0,0 +1,106 @@
+package api
+
+import (
+	"log"
+	"time"
+
+	"streamvault/internal/media"
+	"streamvault/internal/models"
+)
+
+// probeBatch es la cantidad de videos pendientes que se analizan en cada ciclo de la tarea.
+const probeBatch = 20
+
+// probeStaleAfter es cuánto puede estar un video en "probing" antes de que la tarea lo vuelva a
+// tomar. El análisis solo lee las cabeceras, así que tarda segundos incluso en archivos grandes.
+const probeStaleAfter = time.Hour
+
+// probeVideo analiza el archivo del video y guarda el resultado. Un archivo que no se puede leer
+// como MP4/MOV o WebM/Matroska queda marcado como "failed" con el motivo, para revisarlo a mano.
+func (a *App) probeVideo(video *models.Video) error {
+	// Los videos creados sin pasar por la subida (por ejemplo, por la importación del catálogo)
+	// todavía no tienen el tipo MIME.
+	if video.MimeType == "" {
+		if mimeType, err := media.SniffFile(a.videoFilePath(video)); err == nil {
+			if err := a.Store.SetVideoMimeType(video.ID, mimeType); err != nil {
+				return err
+			}
+			video.MimeType = mimeType
+		}
+	}
+	result := models.MediaInfo{Status: models.MediaStatusOK}
+	info, err := media.Probe(a.videoFilePath(video))
+	if err != nil {
+		result = models.MediaInfo{Status: models.MediaStatusFailed, Error: err.Error()}
+	} else {
+		result.Container = info.Container
+		result.DurationSeconds = info.Duration.Seconds()
+		result.Width, result.Height = info.Width, info.Height
+		result.VideoCodec, result.AudioCodec = info.VideoCodec, info.AudioCodec
+		result.Bitrate = info.Bitrate
+		result.CreatedAt = info.CreatedAt
+	}
+	// Si el análisis no obtiene la duración se conserva la que ya se conocía (por ejemplo, la
+	// del NFO de una biblioteca).
+	if result.DurationSeconds == 0 {
+		result.DurationSeconds = video.Media.DurationSeconds
+	}
+	if err := a.Store.SaveMediaInfo(video.ID, &result); err != nil {
+		return err
+	}
+	video.Media = result
+	if result.Status == models.MediaStatusFailed {
+		log.Printf("[Video ID: %d] No se pudo analizar el archivo: %s", video.ID, result.Error)
+	}
+	return nil
+}
+
+// fastStartVideo mueve el índice ('moov') de los MP4/MOV al principio del archivo para que la
+// reproducción empiece sin esperar al final. Si no se puede, el archivo queda como estaba y
+// sigue siendo reproducible, solo que tarda más en empezar. Los archivos de una biblioteca
+// referenciada no se tocan: no son de StreamVault. Si el archivo se reescribe, su nuevo hash se
+// guarda aparte (file_sha256): content_sha256 sigue siendo el de la subida, para que la misma
+// subida se siga detectando como duplicada.
+func (a *App) fastStartVideo(video *models.Video) {
+	if video.SourcePath != "" || (video.Media.Container != "mp4" && video.Media.Container != "mov") {
+		return
+	}
+	changed, err := media.FastStart(a.videoFilePath(video))
+	if err != nil {
+		log.Printf("[Video ID: %d] No se pudo optimizar el archivo para streaming: %v", video.ID, err)
+		return
+	}
+	if !changed {
+		return
+	}
+	log.Printf("[Video ID: %d] Índice 'moov' movido al principio del archivo", video.ID)
+	hash, err := hashFile(a.videoFilePath(video))
+	if err != nil {
+		log.Printf("[Video ID: %d] No se pudo calcular el hash del archivo optimizado: %v", video.ID, err)
+		return
+	}
+	if err := a.Store.SetFileContentHash(video.FilePath, hash); err != nil {
+		log.Printf("[Video ID: %d] No se pudo guardar el hash del archivo optimizado: %v", video.ID, err)
+	}
+}
+
+// probePendingVideos analiza los videos que quedaron sin analizar: los subidos antes de esta
+// función, los creados por la importación del catálogo o los que se cortaron por un reinicio.
+// Cada video se toma marcándolo como "probing", así que nunca se analiza a la vez que lo hace el
+// procesamiento de una subida recién hecha.
+func (a *App) probePendingVideos() error {
+	for {
+		videos, err := a.Store.ClaimVideosPendingProbe(probeBatch, probeStaleAfter)
+		if err != nil {
+			return err
+		}
+		for _, video := range videos {
+			if err := a.probeVideo(video); err != nil {
+				return err
+			}
+		}
+		if len(videos) < probeBatch {
+			return nil
+		}
+	}
+}
@@ -3,6 +3,7 @@ package api
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -25,29 +26,50 @@ func (m *middleware) AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		claims := &models.Claims{}
-
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(m.app.JwtSecret), nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := m.parseToken(authHeader)
+		if err != nil {
 			http.Error(w, "Token inválido", http.StatusUnauthorized)
 			return
 		}
 
 		// Pasar los claims (información del usuario) al siguiente manejador a través del contexto.
-		ctx := context.WithValue(r.Context(), "userClaims", claims)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, withClaims(r, claims))
+	})
+}
+
+// OptionalAuthMiddleware funciona como AuthMiddleware, pero nunca rechaza la petición:
+// si el token falta o es inválido, el manejador simplemente se ejecuta como anónimo.
+func (m *middleware) OptionalAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if claims, err := m.parseToken(r.Header.Get("Authorization")); err == nil {
+			r = withClaims(r, claims)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// parseToken valida el encabezado "Authorization: Bearer <jwt>" y devuelve sus claims.
+func (m *middleware) parseToken(authHeader string) (*models.Claims, error) {
+	if authHeader == "" {
+		return nil, errors.New("falta el token")
+	}
+	tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+	claims := &models.Claims{}
+
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(m.app.JwtSecret), nil
 	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("token inválido")
+	}
+	return claims, nil
 }
 
 // AdminOnlyMiddleware verifica que el rol del usuario sea 'admin'.
 func (m *middleware) AdminOnlyMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Se asume que AuthMiddleware ya se ejecutó y pobló el contexto.
-		claims, ok := r.Context().Value("userClaims").(*models.Claims)
+		claims, ok := claimsFromContext(r)
 		if !ok || claims.Role != "admin" {
 			http.Error(w, "Acceso denegado: se requiere rol de administrador", http.StatusForbidden)
 			return
@@ -56,3 +78,20 @@ func (m *middleware) AdminOnlyMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// withClaims devuelve la petición con los claims del usuario en su contexto.
+func withClaims(r *http.Request, claims *models.Claims) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), "userClaims", claims))
+}
+
+// claimsFromContext recupera los claims que AuthMiddleware dejó en el contexto de la petición.
+func claimsFromContext(r *http.Request) (*models.Claims, bool) {
+	claims, ok := r.Context().Value("userClaims").(*models.Claims)
+	return claims, ok
+}
+
+// isAdmin indica si la petición viene de un usuario autenticado con rol 'admin'.
+func isAdmin(r *http.Request) bool {
+	claims, ok := claimsFromContext(r)
+	return ok && claims.Role == "admin"
+}
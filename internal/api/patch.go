@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"streamvault/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// setVideoETag expone la versión del video como ETag, para que el cliente la devuelva en If-Match.
+func setVideoETag(w http.ResponseWriter, video *models.Video) {
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, video.Version))
+}
+
+// parseIfMatch interpreta el encabezado If-Match. Devuelve 0 si está vacío o es "*" (cualquier versión).
+// ok es false si el valor no corresponde a un ETag emitido por setVideoETag.
+func parseIfMatch(header string) (version int, ok bool) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, true
+	}
+	header = strings.TrimPrefix(header, "W/")
+	version, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
+
+// mergePatch aplica un JSON Merge Patch (RFC 7396) sobre 'target'. Los objetos se combinan
+// recursivamente, un null elimina la clave y cualquier otro valor la reemplaza por completo.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
+
+// HandlePatchVideo actualiza solo los campos enviados de un video, con semántica JSON Merge Patch.
+// Exige If-Match con el ETag actual: si otra persona editó el video entretanto, responde 412.
+func (h *handler) HandlePatchVideo(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	if r.Header.Get("If-Match") == "" {
+		respondWithError(w, http.StatusPreconditionRequired, "Falta el encabezado If-Match con el ETag del video")
+		return
+	}
+	expected, ok := parseIfMatch(r.Header.Get("If-Match"))
+	if !ok {
+		respondWithError(w, http.StatusPreconditionFailed, "El encabezado If-Match no es válido")
+		return
+	}
+	existing, err := h.app.Store.GetVideoByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	if !canEditVideo(r, existing) {
+		respondWithError(w, http.StatusForbidden, "Solo puedes editar tus propios videos")
+		return
+	}
+	if expected != 0 && expected != existing.Version {
+		respondWithError(w, http.StatusPreconditionFailed, "El video fue modificado por otra persona. Recárgalo y vuelve a intentarlo.")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Request inválido")
+		return
+	}
+	var patch map[string]interface{}
+	if err := json.Unmarshal(body, &patch); err != nil {
+		respondWithError(w, http.StatusBadRequest, "El cuerpo debe ser un objeto JSON (application/merge-patch+json)")
+		return
+	}
+	// Se aplica el parche sobre la representación JSON actual del video y el resultado se
+	// vuelve a decodificar; los campos no editables se restauran en saveVideoEdit.
+	current, _ := json.Marshal(existing)
+	var doc interface{}
+	json.Unmarshal(current, &doc)
+	merged, _ := json.Marshal(mergePatch(doc, patch))
+	var updatedVideo models.Video
+	if err := json.Unmarshal(merged, &updatedVideo); err != nil {
+		respondWithError(w, http.StatusBadRequest, "El parche contiene valores con un tipo inválido")
+		return
+	}
+	h.saveVideoEdit(w, r, existing, &updatedVideo, existing.Version)
+}
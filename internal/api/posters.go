@@ -0,0 +1,188 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"streamvault/internal/fsutil"
+	"streamvault/internal/imaging"
+	"streamvault/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// maxPosterSize es el tamaño máximo del archivo de portada original.
+const maxPosterSize = 10 << 20
+
+// posterDir es la carpeta donde se guardan las variantes de la portada de un video.
+func (a *App) posterDir(videoID int) string {
+	return filepath.Join(a.UploadDir, "posters", strconv.Itoa(videoID))
+}
+
+// readPoster lee y decodifica la portada enviada en el campo 'poster' de un formulario.
+// Devuelve el código HTTP adecuado junto al error para que el handler solo tenga que responder.
+func readPoster(file io.Reader) (image.Image, int, error) {
+	data, err := io.ReadAll(io.LimitReader(file, maxPosterSize+1))
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.New("no se pudo leer la portada")
+	}
+	if len(data) > maxPosterSize {
+		return nil, http.StatusRequestEntityTooLarge, errors.New("la portada es demasiado grande (límite 10MB)")
+	}
+	img, err := imaging.Decode(data)
+	if errors.Is(err, imaging.ErrUnsupportedFormat) {
+		return nil, http.StatusUnsupportedMediaType, errors.New("formato de portada no soportado, usa JPEG, PNG o GIF")
+	}
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("portada inválida: %v", err)
+	}
+	return img, 0, nil
+}
+
+// savePoster genera las variantes de la portada y las guarda en disco. Cada variante se
+// escribe con un nombre temporal y luego se renombra, para no servir nunca un archivo a medias.
+func (a *App) savePoster(videoID int, img image.Image) error {
+	dir := a.posterDir(videoID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// Cada variante se reduce a partir de la anterior, que ya es más chica que el original.
+	current := img
+	for _, variant := range imaging.Variants {
+		resized := imaging.Resize(current, variant.Width)
+		var buf bytes.Buffer
+		if err := imaging.EncodeJPEG(&buf, resized); err != nil {
+			return err
+		}
+		if err := fsutil.WriteFile(filepath.Join(dir, variant.Name+".jpg"), buf.Bytes(), 0644); err != nil {
+			return err
+		}
+		current = resized
+	}
+	now := time.Now()
+	if err := a.Store.SetVideoPoster(videoID, &now); err != nil {
+		return err
+	}
+	a.publishVideoEvent(videoID, eventThumbnails, map[string]interface{}{"poster": models.NewPoster(videoID, now)})
+	return nil
+}
+
+// removePoster borra las variantes de la portada de un video, si las tiene.
+func (a *App) removePoster(videoID int) {
+	if err := os.RemoveAll(a.posterDir(videoID)); err != nil {
+		log.Printf("[Video ID: %d] Error al borrar la portada: %v", videoID, err)
+	}
+}
+
+// HandleUploadPoster agrega o reemplaza la portada de un video. La imagen llega en el campo
+// 'poster' y su formato se comprueba por su contenido, no por el nombre del archivo.
+func (h *handler) HandleUploadPoster(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	video, err := h.app.Store.GetVideoByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	if !canEditVideo(r, video) {
+		respondWithError(w, http.StatusForbidden, "Solo puedes editar tus propios videos")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxPosterSize+64<<10)
+	if err := r.ParseMultipartForm(maxPosterSize); err != nil {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "La portada es demasiado grande (límite 10MB)")
+		return
+	}
+	file, _, err := r.FormFile("poster")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Petición inválida: Falta la imagen con la clave 'poster'")
+		return
+	}
+	defer file.Close()
+	img, status, err := readPoster(file)
+	if err != nil {
+		respondWithError(w, status, err.Error())
+		return
+	}
+	if err := h.app.savePoster(id, img); err != nil {
+		log.Printf("[Video ID: %d] Error al guardar la portada: %v", id, err)
+		respondWithError(w, http.StatusInternalServerError, "Error al guardar la portada")
+		return
+	}
+	video, err = h.app.Store.GetVideoByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al obtener el video")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, video.Poster)
+}
+
+// HandleDeletePoster quita la portada de un video.
+func (h *handler) HandleDeletePoster(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	video, err := h.app.Store.GetVideoByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	if !canEditVideo(r, video) {
+		respondWithError(w, http.StatusForbidden, "Solo puedes editar tus propios videos")
+		return
+	}
+	if err := h.app.Store.SetVideoPoster(id, nil); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al quitar la portada")
+		return
+	}
+	h.app.removePoster(id)
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Portada eliminada"})
+}
+
+// HandleGetPoster sirve una variante de la portada (small, medium o large).
+// Las URLs que devuelve la API llevan la fecha de la portada en '?v=', así que si coincide
+// con la actual la respuesta puede guardarse en caché indefinidamente.
+func (h *handler) HandleGetPoster(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	video, err := h.app.Store.GetVideoByID(id)
+	if err != nil || !canSeeVideo(r, video) || video.Poster == nil {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := os.Open(filepath.Join(h.app.posterDir(id), vars["size"]+".jpg"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	switch {
+	case !video.IsAvailableAt(time.Now()):
+		// Solo quien puede ver videos fuera de su ventana llega aquí; no debe quedar en cachés compartidas.
+		w.Header().Set("Cache-Control", "private, no-cache")
+	case r.URL.Query().Get("v") == strconv.FormatInt(video.Poster.UpdatedAt.Unix(), 10):
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	default:
+		w.Header().Set("Cache-Control", "public, max-age=300")
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	http.ServeContent(w, r, "", video.Poster.UpdatedAt, f)
+}
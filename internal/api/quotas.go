@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"streamvault/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// effectiveQuota calcula la cuota efectiva del usuario: cada límite propio del usuario reemplaza
+// al de su rol, y si ninguno lo define, ese límite no existe.
+func (a *App) effectiveQuota(userID int, role string) (models.Quota, error) {
+	own, err := a.Store.GetUserQuota(userID)
+	if err != nil {
+		return models.Quota{}, err
+	}
+	quota := a.RoleQuotas[role]
+	if own.MaxBytes != nil {
+		quota.MaxBytes = own.MaxBytes
+	}
+	if own.MaxVideos != nil {
+		quota.MaxVideos = own.MaxVideos
+	}
+	return quota, nil
+}
+
+// quotaUsage calcula el consumo del usuario frente a su cuota efectiva.
+func (h *handler) quotaUsage(userID int, role string) (*models.QuotaUsage, error) {
+	quota, err := h.app.effectiveQuota(userID, role)
+	if err != nil {
+		return nil, err
+	}
+	usage := &models.QuotaUsage{Quota: quota}
+	usage.UsedBytes, usage.UsedVideos, err = h.app.Store.GetUploadUsage(userID)
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// checkUploadQuota verifica que subir un archivo de 'size' bytes no supere la cuota del usuario.
+// Si la supera, 'exceeded' contiene un mensaje apto para mostrar al cliente. Sirve para rechazar
+// cuanto antes; la comprobación definitiva se hace al crear el video (ver registerUploadedVideo).
+func (h *handler) checkUploadQuota(claims *models.Claims, size int64) (exceeded string, err error) {
+	usage, err := h.quotaUsage(claims.UserID, claims.Role)
+	if err != nil {
+		return "", err
+	}
+	if usage.MaxVideos != nil && usage.UsedVideos+1 > *usage.MaxVideos {
+		return fmt.Sprintf("Cuota excedida: ya subiste %d de %d videos permitidos", usage.UsedVideos, *usage.MaxVideos), nil
+	}
+	if usage.MaxBytes != nil && usage.UsedBytes+size > *usage.MaxBytes {
+		return fmt.Sprintf("Cuota excedida: el archivo ocupa %d bytes y te quedan %d", size, *usage.MaxBytes-usage.UsedBytes), nil
+	}
+	return "", nil
+}
+
+// checkQuotaOrRespond es checkUploadQuota para los handlers: si la subida no entra en la cuota
+// (o no se pudo comprobar) responde con el error y devuelve false.
+func (h *handler) checkQuotaOrRespond(w http.ResponseWriter, claims *models.Claims, size int64) bool {
+	exceeded, err := h.checkUploadQuota(claims, size)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al verificar la cuota")
+		return false
+	}
+	if exceeded != "" {
+		respondWithError(w, http.StatusForbidden, exceeded)
+		return false
+	}
+	return true
+}
+
+// canEditVideo indica si quien hace la petición puede modificar o borrar el video:
+// los admins pueden con cualquiera y los usuarios solo con los que subieron.
+func canEditVideo(r *http.Request, video *models.Video) bool {
+	if isAdmin(r) {
+		return true
+	}
+	claims, ok := claimsFromContext(r)
+	return ok && video.UploaderID != nil && *video.UploaderID == claims.UserID
+}
+
+// HandleGetMyQuota muestra al usuario cuánto lleva subido y cuánto le permite su cuota.
+func (h *handler) HandleGetMyQuota(w http.ResponseWriter, r *http.Request) {
+	claims, ok := claimsFromContext(r)
+	if !ok || claims.UserID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "Sesión inválida, vuelve a iniciar sesión")
+		return
+	}
+	usage, err := h.quotaUsage(claims.UserID, claims.Role)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al obtener la cuota")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, usage)
+}
+
+// HandleListMyVideos devuelve los videos que subió el usuario autenticado.
+func (h *handler) HandleListMyVideos(w http.ResponseWriter, r *http.Request) {
+	claims, ok := claimsFromContext(r)
+	if !ok || claims.UserID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "Sesión inválida, vuelve a iniciar sesión")
+		return
+	}
+	videos, err := h.app.Store.GetVideosByUploader(claims.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "No se pudieron obtener los videos")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, videos)
+}
+
+// HandleAdminSetUserQuota asigna una cuota propia a un usuario (solo para admins).
+// Enviar null en un campo hace que ese límite vuelva a tomarse del rol.
+func (h *handler) HandleAdminSetUserQuota(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de usuario inválido")
+		return
+	}
+	var quota models.Quota
+	if err := json.NewDecoder(r.Body).Decode(&quota); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Cuerpo de la petición inválido")
+		return
+	}
+	if (quota.MaxBytes != nil && *quota.MaxBytes < 0) || (quota.MaxVideos != nil && *quota.MaxVideos < 0) {
+		respondWithError(w, http.StatusBadRequest, "Los límites no pueden ser negativos")
+		return
+	}
+	if err := h.app.Store.SetUserQuota(id, quota); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al actualizar la cuota del usuario")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Cuota del usuario actualizada exitosamente"})
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"streamvault/internal/fsutil"
+	"streamvault/internal/models"
+)
+
+const (
+	// quarantineDirName es el subdirectorio de UPLOAD_DIR al que se mueven los archivos huérfanos.
+	quarantineDirName = ".quarantine"
+	// reconcileGracePeriod protege a los archivos recién escritos: entre que una subida termina
+	// de escribir el archivo y se crea su registro pasa un momento en el que parece huérfano.
+	reconcileGracePeriod = time.Hour
+)
+
+// reconcileFiles compara los archivos de UPLOAD_DIR con los videos registrados. Los archivos que
+// ningún video usa (por ejemplo, los que quedaron de una subida cortada antes de crear el
+// registro) se informan o, si 'quarantine' es true, se mueven a UPLOAD_DIR/.quarantine para
+// revisarlos a mano. Los videos cuyo archivo falta (en UPLOAD_DIR o, si se importaron sin copiarlo,
+// en su biblioteca) solo se informan.
+func (a *App) reconcileFiles(quarantine bool) (*models.ReconcileReport, error) {
+	files, err := a.Store.GetVideoFiles()
+	if err != nil {
+		return nil, err
+	}
+	// Un archivo puede estar compartido por varios videos creados como duplicados.
+	referenced := make(map[string]bool, len(files))
+	report := &models.ReconcileReport{Quarantine: quarantine, OrphanFiles: []models.OrphanFile{}, MissingFiles: []models.VideoFile{}}
+	for _, f := range files {
+		referenced[f.FilePath] = true
+		path := f.SourcePath
+		if path == "" {
+			path = filepath.Join(a.UploadDir, f.FilePath)
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			report.MissingFiles = append(report.MissingFiles, f)
+		}
+	}
+
+	entries, err := os.ReadDir(a.UploadDir)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-reconcileGracePeriod)
+	for _, entry := range entries {
+		// Los subdirectorios (portadas, subidas reanudables, cuarentena) no son videos.
+		if !entry.Type().IsRegular() || referenced[entry.Name()] {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		orphan := models.OrphanFile{
+			Name:       entry.Name(),
+			SizeBytes:  info.Size(),
+			ModifiedAt: info.ModTime(),
+			Temporary:  fsutil.IsTemp(entry.Name()),
+		}
+		if quarantine {
+			if err := a.quarantineFile(entry.Name()); err != nil {
+				log.Printf("No se pudo mover %s a la cuarentena: %v", entry.Name(), err)
+			} else {
+				orphan.QuarantinedAs = filepath.Join(quarantineDirName, entry.Name())
+			}
+		}
+		report.OrphanFiles = append(report.OrphanFiles, orphan)
+	}
+	return report, nil
+}
+
+// quarantineFile mueve un archivo de UPLOAD_DIR a la cuarentena.
+func (a *App) quarantineFile(name string) error {
+	dir := filepath.Join(a.UploadDir, quarantineDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return fsutil.Rename(filepath.Join(a.UploadDir, name), filepath.Join(dir, name))
+}
+
+// reconcileUploadDir es la tarea periódica de reconciliación; deja el resultado en el log.
+func (a *App) reconcileUploadDir() error {
+	report, err := a.reconcileFiles(a.QuarantineOrphans)
+	if err != nil {
+		return err
+	}
+	for _, orphan := range report.OrphanFiles {
+		if orphan.QuarantinedAs != "" {
+			log.Printf("Archivo huérfano movido a la cuarentena: %s (%d bytes)", orphan.Name, orphan.SizeBytes)
+		} else {
+			log.Printf("Archivo huérfano en UPLOAD_DIR: %s (%d bytes)", orphan.Name, orphan.SizeBytes)
+		}
+	}
+	for _, missing := range report.MissingFiles {
+		path := missing.FilePath
+		if missing.SourcePath != "" {
+			path = missing.SourcePath
+		}
+		log.Printf("[Video ID: %d] No existe el archivo %s", missing.VideoID, path)
+	}
+	return nil
+}
+
+// HandleReconcileFiles ejecuta la reconciliación en el momento y devuelve el informe. Con
+// ?quarantine=true los archivos huérfanos se mueven a la cuarentena; si no, solo se informan.
+func (h *handler) HandleReconcileFiles(w http.ResponseWriter, r *http.Request) {
+	report, err := h.app.reconcileFiles(r.URL.Query().Get("quarantine") == "true")
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al revisar los archivos")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"streamvault/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// maxRelatedVideos es la cantidad de recomendaciones que se precalculan por video.
+const maxRelatedVideos = 20
+
+// Pesos de cada señal en la puntuación final. Cada señal está normalizada entre 0 y 1.
+const (
+	weightCategory = 2.0
+	weightTags     = 3.0
+	weightTitle    = 2.0
+	weightCoWatch  = 3.0
+)
+
+// titleStopWords son palabras demasiado comunes como para indicar que dos títulos se parecen.
+var titleStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "los": true, "las": true,
+	"del": true, "con": true, "para": true, "una": true, "por": true, "que": true,
+}
+
+// titleTerms divide un título en términos en minúsculas, sin puntuación ni palabras vacías.
+func titleTerms(title string) map[string]bool {
+	terms := make(map[string]bool)
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		if len([]rune(word)) < 3 || titleStopWords[word] {
+			continue
+		}
+		terms[word] = true
+	}
+	return terms
+}
+
+// jaccard mide la similitud entre dos conjuntos: tamaño de la intersección entre tamaño de la unión.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for term := range a {
+		if b[term] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func tagSet(tags []string) map[string]bool {
+	set := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		set[tag] = true
+	}
+	return set
+}
+
+// scoreRelated puntúa cada candidato respecto a 'target' y devuelve los mejores, ordenados.
+// coWatch indica cuántos espectadores vieron 'target' y cada candidato; puede ser nil.
+func scoreRelated(target *models.Video, candidates []*models.Video, coWatch map[int]int, limit int) []models.RelatedScore {
+	maxCoWatch := 0
+	for _, n := range coWatch {
+		if n > maxCoWatch {
+			maxCoWatch = n
+		}
+	}
+	targetTags := tagSet(target.Tags)
+	targetTerms := titleTerms(target.Title)
+
+	var scores []models.RelatedScore
+	for _, c := range candidates {
+		if c.ID == target.ID {
+			continue
+		}
+		score := weightTags*jaccard(targetTags, tagSet(c.Tags)) +
+			weightTitle*jaccard(targetTerms, titleTerms(c.Title))
+		if strings.EqualFold(c.Category, target.Category) {
+			score += weightCategory
+		}
+		if maxCoWatch > 0 {
+			score += weightCoWatch * float64(coWatch[c.ID]) / float64(maxCoWatch)
+		}
+		if score > 0 {
+			scores = append(scores, models.RelatedScore{VideoID: c.ID, Score: score})
+		}
+	}
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+	if len(scores) > limit {
+		scores = scores[:limit]
+	}
+	return scores
+}
+
+// refreshRelatedVideos recalcula las recomendaciones de todo el catálogo y las guarda en la BD.
+func (a *App) refreshRelatedVideos() error {
+	videos, err := a.Store.GetAllVideos()
+	if err != nil {
+		return err
+	}
+	pairs, err := a.Store.GetCoWatchCounts()
+	if err != nil {
+		return err
+	}
+	coWatch := make(map[int]map[int]int)
+	for _, p := range pairs {
+		if coWatch[p.VideoA] == nil {
+			coWatch[p.VideoA] = make(map[int]int)
+		}
+		if coWatch[p.VideoB] == nil {
+			coWatch[p.VideoB] = make(map[int]int)
+		}
+		coWatch[p.VideoA][p.VideoB] = p.Viewers
+		coWatch[p.VideoB][p.VideoA] = p.Viewers
+	}
+	related := make(map[int][]models.RelatedScore, len(videos))
+	for _, video := range videos {
+		related[video.ID] = scoreRelated(video, videos, coWatch[video.ID], maxRelatedVideos)
+	}
+	return a.Store.ReplaceRelatedVideos(related)
+}
+
+// HandleGetRelatedVideos devuelve los videos recomendados para ver después de uno dado.
+// Si la tarea en segundo plano todavía no calculó recomendaciones para este video (por ejemplo,
+// porque se acaba de subir), se devuelven los más recientes de su categoría: puntuar todo el
+// catálogo en cada petición sería demasiado costoso.
+func (h *handler) HandleGetRelatedVideos(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 || limit > maxRelatedVideos {
+		limit = 10
+	}
+	video, err := h.app.Store.GetVideoByID(id)
+	if err != nil || !canSeeVideo(r, video) {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	// Se piden todas las precalculadas porque algunas pueden quedar fuera al filtrar por visibilidad.
+	related, err := h.app.Store.GetRelatedVideos(id, maxRelatedVideos)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "No se pudieron obtener las recomendaciones")
+		return
+	}
+	if len(related) == 0 {
+		related, err = h.app.Store.GetVideosInCategory(video.Category, video.ID, limit, !isAdmin(r))
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "No se pudieron obtener las recomendaciones")
+			return
+		}
+	}
+	visible := []*models.Video{}
+	for _, v := range related {
+		if len(visible) == limit {
+			break
+		}
+		if canSeeVideo(r, v) {
+			visible = append(visible, v)
+		}
+	}
+	respondWithJSON(w, http.StatusOK, visible)
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"streamvault/internal/models"
+	"streamvault/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// recordRevision guarda los metadatos de 'after' en el historial del video. 'before' es el estado
+// previo (nil al subir un video nuevo); la capa de datos lo usa como revisión base si el video
+// todavía no tenía historial. Las ediciones no lo usan: UpdateVideo guarda la revisión en su
+// propia transacción.
+// Un fallo aquí no revierte el video ya creado; solo se registra en el log.
+func (a *App) recordRevision(before, after *models.Video, authorID int) {
+	if err := a.Store.SaveVideoRevision(before, after, authorID); err != nil {
+		log.Printf("[Video ID: %d] Error al guardar la revisión: %v", after.ID, err)
+	}
+}
+
+// diffRevisions lista los campos que cambiaron de 'prev' a 'cur'. Si prev es nil (la primera
+// revisión), todos los campos se reportan con valor anterior null.
+func diffRevisions(prev, cur *models.VideoRevision) []models.FieldChange {
+	first := prev == nil
+	if first {
+		prev = &models.VideoRevision{}
+	}
+	fields := []models.FieldChange{
+		{Field: "title", Old: prev.Title, New: cur.Title},
+		{Field: "description", Old: prev.Description, New: cur.Description},
+		{Field: "category", Old: prev.Category, New: cur.Category},
+		{Field: "tags", Old: prev.Tags, New: cur.Tags},
+	}
+	changes := []models.FieldChange{}
+	for _, change := range fields {
+		if first {
+			change.Old = nil
+		} else if reflect.DeepEqual(change.Old, change.New) {
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// HandleListVideoRevisions devuelve el historial de un video con los cambios de cada revisión (solo para admins).
+func (h *handler) HandleListVideoRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	if _, err := h.app.Store.GetVideoByID(id); err != nil {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	revisions, err := h.app.Store.GetVideoRevisions(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al obtener el historial")
+		return
+	}
+	var prev *models.VideoRevision
+	for _, rev := range revisions {
+		rev.Changes = diffRevisions(prev, rev)
+		prev = rev
+	}
+	respondWithJSON(w, http.StatusOK, revisions)
+}
+
+// HandleRestoreVideoRevision vuelve los metadatos del video a los de una revisión anterior (solo para admins).
+// La restauración se guarda a su vez como una revisión nueva, así que también puede deshacerse.
+func (h *handler) HandleRestoreVideoRevision(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	revNumber, err := strconv.Atoi(vars["rev"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Número de revisión inválido")
+		return
+	}
+	video, err := h.app.Store.GetVideoByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	rev, err := h.app.Store.GetVideoRevision(id, revNumber)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Revisión no encontrada")
+		return
+	}
+	video.Title = rev.Title
+	video.Description = rev.Description
+	video.Category = rev.Category
+	video.Tags = rev.Tags
+	claims, _ := claimsFromContext(r)
+	if err := h.app.Store.UpdateVideo(video, claims.UserID); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			respondWithError(w, http.StatusPreconditionFailed, "El video fue modificado por otra persona. Recárgalo y vuelve a intentarlo.")
+			return
+		}
+		if errors.Is(err, storage.ErrVideoNotFound) {
+			respondWithError(w, http.StatusNotFound, "Video no encontrado")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error al restaurar la revisión")
+		return
+	}
+	h.app.syncDescriptionChapters(video)
+	setVideoETag(w, video)
+	respondWithJSON(w, http.StatusOK, video)
+}
@@ -35,28 +35,91 @@ func NewRouter(app *App) http.Handler {
 	// Definimos las rutas públicas de la API.
 	apiRouter.HandleFunc("/register", h.HandleRegisterUser).Methods("POST")
 	apiRouter.HandleFunc("/login", h.HandleLoginUser).Methods("POST")
-	apiRouter.HandleFunc("/videos", h.HandleListVideos).Methods("GET")
-	apiRouter.HandleFunc("/videos/{id:[0-9]+}", h.HandleGetVideoByID).Methods("GET")
+	// Las rutas públicas de videos usan autenticación opcional: los admins también ven
+	// los videos programados o vencidos, y las vistas se atribuyen al usuario si envía su token.
+	videoRoutes := apiRouter.PathPrefix("/videos").Subrouter()
+	videoRoutes.Use(m.OptionalAuthMiddleware)
+	videoRoutes.HandleFunc("", h.HandleListVideos).Methods("GET")
+	videoRoutes.HandleFunc("/search", h.HandleSearchVideos).Methods("GET")
+	videoRoutes.HandleFunc("/{id:[0-9]+}", h.HandleGetVideoByID).Methods("GET")
+	videoRoutes.HandleFunc("/{id:[0-9]+}/related", h.HandleGetRelatedVideos).Methods("GET")
+	videoRoutes.HandleFunc("/{id:[0-9]+}/beacon", h.HandleVideoBeacon).Methods("POST")
+	videoRoutes.HandleFunc("/{id:[0-9]+}/subtitles/{lang:[a-zA-Z0-9-]+}.vtt", h.HandleGetSubtitle).Methods("GET")
+	videoRoutes.HandleFunc("/{id:[0-9]+}/chapters.vtt", h.HandleGetChaptersVTT).Methods("GET")
+	videoRoutes.HandleFunc("/{id:[0-9]+}/poster/{size:small|medium|large}.jpg", h.HandleGetPoster).Methods("GET")
+
+	// Enlaces firmados: no llevan token, la firma del enlace autoriza la operación.
+	signedRoutes := apiRouter.PathPrefix("/signed").Subrouter()
+	signedRoutes.HandleFunc("/upload", h.HandleSignedUpload).Methods("POST")
+	signedRoutes.HandleFunc("/videos/{id:[0-9]+}/download", h.HandleSignedDownload).Methods("GET")
+
+	// Rutas personales del usuario autenticado (cualquier rol).
+	meRoutes := apiRouter.PathPrefix("/me").Subrouter()
+	meRoutes.Use(m.AuthMiddleware)
+	meRoutes.HandleFunc("/{list:favorites|watch-later}", h.HandleListBookmarks).Methods("GET")
+	meRoutes.HandleFunc("/{list:favorites|watch-later}/{videoId:[0-9]+}", h.HandleAddBookmark).Methods("PUT")
+	meRoutes.HandleFunc("/{list:favorites|watch-later}/{videoId:[0-9]+}", h.HandleRemoveBookmark).Methods("DELETE")
+	meRoutes.HandleFunc("/quota", h.HandleGetMyQuota).Methods("GET")
+	meRoutes.HandleFunc("/upload", h.HandleUploadVideo).Methods("POST")
+	meRoutes.HandleFunc("/videos", h.HandleListMyVideos).Methods("GET")
+	meRoutes.HandleFunc("/videos/{id:[0-9]+}", h.HandleUpdateVideo).Methods("PUT")
+	meRoutes.HandleFunc("/videos/{id:[0-9]+}", h.HandlePatchVideo).Methods("PATCH")
+	meRoutes.HandleFunc("/videos/{id:[0-9]+}", h.HandleDeleteVideo).Methods("DELETE")
+	meRoutes.HandleFunc("/videos/{id:[0-9]+}/subtitles", h.HandleUploadSubtitle).Methods("POST")
+	meRoutes.HandleFunc("/videos/{id:[0-9]+}/chapters", h.HandleUpdateChapters).Methods("PUT")
+	meRoutes.HandleFunc("/videos/{id:[0-9]+}/poster", h.HandleUploadPoster).Methods("PUT")
+	meRoutes.HandleFunc("/videos/{id:[0-9]+}/poster", h.HandleDeletePoster).Methods("DELETE")
+	meRoutes.HandleFunc("/videos/{id:[0-9]+}/subtitles/{lang:[a-zA-Z0-9-]+}", h.HandleDeleteSubtitle).Methods("DELETE")
 
 	// Definimos las rutas de administrador protegidas.
 	adminRoutes := apiRouter.PathPrefix("/admin").Subrouter()
 	adminRoutes.Use(m.AuthMiddleware, m.AdminOnlyMiddleware)
 	adminRoutes.HandleFunc("/upload", h.HandleUploadVideo).Methods("POST")
+	// Subidas reanudables (protocolo tus 1.0).
+	adminRoutes.HandleFunc("/uploads", tusMiddleware(h.HandleTusCreate)).Methods("POST")
+	adminRoutes.HandleFunc("/uploads/{uploadId:[0-9a-f]{32}}", tusMiddleware(h.HandleTusHead)).Methods("HEAD")
+	adminRoutes.HandleFunc("/uploads/{uploadId:[0-9a-f]{32}}", tusMiddleware(h.HandleTusPatch)).Methods("PATCH")
+	adminRoutes.HandleFunc("/uploads/{uploadId:[0-9a-f]{32}}", tusMiddleware(h.HandleTusDelete)).Methods("DELETE")
+	adminRoutes.HandleFunc("/signed-urls/upload", h.HandleCreateUploadURL).Methods("POST")
+	adminRoutes.HandleFunc("/videos/bulk", h.HandleBulkVideos).Methods("POST")
+	adminRoutes.HandleFunc("/catalog/export", h.HandleExportCatalog).Methods("GET")
+	adminRoutes.HandleFunc("/catalog/import", h.HandleImportCatalog).Methods("POST")
+	adminRoutes.HandleFunc("/library/import", h.HandleImportLibrary).Methods("POST")
+	adminRoutes.HandleFunc("/trash", h.HandleListTrash).Methods("GET")
+	adminRoutes.HandleFunc("/reconcile", h.HandleReconcileFiles).Methods("POST")
+	adminRoutes.HandleFunc("/trash/{id:[0-9]+}/restore", h.HandleRestoreVideo).Methods("POST")
+	adminRoutes.HandleFunc("/users/bulk", h.HandleBulkUsers).Methods("POST")
 	adminRoutes.HandleFunc("/videos/{id:[0-9]+}", h.HandleUpdateVideo).Methods("PUT")
+	adminRoutes.HandleFunc("/videos/{id:[0-9]+}", h.HandlePatchVideo).Methods("PATCH")
 	adminRoutes.HandleFunc("/videos/{id:[0-9]+}", h.HandleDeleteVideo).Methods("DELETE")
+	adminRoutes.HandleFunc("/videos/{id:[0-9]+}/stats", h.HandleVideoStats).Methods("GET")
+	adminRoutes.HandleFunc("/videos/{id:[0-9]+}/download-url", h.HandleCreateDownloadURL).Methods("POST")
+	adminRoutes.HandleFunc("/videos/{id:[0-9]+}/events", h.HandleVideoEvents).Methods("GET")
+	adminRoutes.HandleFunc("/videos/{id:[0-9]+}/subtitles", h.HandleUploadSubtitle).Methods("POST")
+	adminRoutes.HandleFunc("/videos/{id:[0-9]+}/chapters", h.HandleUpdateChapters).Methods("PUT")
+	adminRoutes.HandleFunc("/videos/{id:[0-9]+}/poster", h.HandleUploadPoster).Methods("PUT")
+	adminRoutes.HandleFunc("/videos/{id:[0-9]+}/poster", h.HandleDeletePoster).Methods("DELETE")
+	adminRoutes.HandleFunc("/videos/{id:[0-9]+}/subtitles/{lang:[a-zA-Z0-9-]+}", h.HandleDeleteSubtitle).Methods("DELETE")
+	adminRoutes.HandleFunc("/videos/{id:[0-9]+}/revisions", h.HandleListVideoRevisions).Methods("GET")
+	adminRoutes.HandleFunc("/videos/{id:[0-9]+}/revisions/{rev:[0-9]+}/restore", h.HandleRestoreVideoRevision).Methods("POST")
 	adminRoutes.HandleFunc("/users", h.HandleListAllUsers).Methods("GET")
 	adminRoutes.HandleFunc("/users/{id:[0-9]+}/role", h.HandleAdminUpdateUserRole).Methods("PUT")
+	adminRoutes.HandleFunc("/users/{id:[0-9]+}/quota", h.HandleAdminSetUserQuota).Methods("PUT")
 	adminRoutes.HandleFunc("/users/{id:[0-a-9]+}", h.HandleAdminDeleteUser).Methods("DELETE")
 	// La ruta de streaming es una ruta especial para servir archivos.
-	r.HandleFunc("/stream/{filename}", h.HandleStreamVideo).Methods("GET")
+	// Usa autenticación opcional para atribuir la vista al usuario cuando envía su token.
+	r.Handle("/stream/{filename}", m.OptionalAuthMiddleware(http.HandlerFunc(h.HandleStreamVideo))).Methods("GET")
 
 	// Servidor de archivos estáticos para el frontend (debe ser la última regla de enrutamiento).
 	r.PathPrefix("/").Handler(http.FileServer(http.Dir("./web/")))
 
 	// --- Configuración de CORS ---
 	allowedOrigins := handlers.AllowedOrigins([]string{"*"})
-	allowedMethods := handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
-	allowedHeaders := handlers.AllowedHeaders([]string{"Authorization", "Content-Type"})
+	allowedMethods := handlers.AllowedMethods([]string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"})
+	allowedHeaders := handlers.AllowedHeaders([]string{"Authorization", "Content-Type", "If-Match", "Last-Event-ID",
+		"Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata"})
+	exposedHeaders := handlers.ExposedHeaders([]string{"ETag", "Location", "Tus-Resumable", "Tus-Version",
+		"Tus-Extension", "Tus-Max-Size", "Upload-Offset", "Upload-Length", "Upload-Metadata", "Upload-Expires", "Video-Id"})
 
-	return handlers.CORS(allowedOrigins, allowedMethods, allowedHeaders)(r)
+	return h.tusOptionsMiddleware(handlers.CORS(allowedOrigins, allowedMethods, allowedHeaders, exposedHeaders)(r))
 }
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"streamvault/internal/models"
+)
+
+// VideoPublishedListener recibe cada video en el momento en que se publica, por ejemplo,
+// para enviar notificaciones a los usuarios.
+type VideoPublishedListener func(video *models.Video)
+
+// OnVideoPublished registra un listener que el programador de publicaciones llamará por cada
+// video que llegue a su fecha de publicación. Debe llamarse antes de StartBackgroundJobs.
+func (a *App) OnVideoPublished(listener VideoPublishedListener) {
+	a.publishedListeners = append(a.publishedListeners, listener)
+}
+
+// publishPublicationEvent es el listener de publicación que siempre está registrado: avisa por el
+// stream de eventos del video que ya es visible.
+func (a *App) publishPublicationEvent(video *models.Video) {
+	a.publishVideoEvent(video.ID, eventPublished, map[string]interface{}{
+		"title": video.Title, "publish_at": video.PublishAt,
+	})
+}
+
+// announceDuePublications emite el evento de publicación de los videos cuya fecha ya llegó.
+func (a *App) announceDuePublications() error {
+	videos, err := a.Store.ClaimDuePublications()
+	if err != nil {
+		return err
+	}
+	for _, video := range videos {
+		log.Printf("[Video ID: %d] Publicado: %s", video.ID, video.Title)
+		for _, listener := range a.publishedListeners {
+			listener(video)
+		}
+	}
+	return nil
+}
+
+// canSeeVideo indica si quien hace la petición puede ver el video: los admins ven todo,
+// el resto solo los videos dentro de su ventana de publicación.
+func canSeeVideo(r *http.Request, video *models.Video) bool {
+	return isAdmin(r) || video.IsAvailableAt(time.Now())
+}
+
+// parseScheduleTime interpreta una fecha RFC 3339 del formulario de subida. Vacío significa nil.
+func parseScheduleTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// validateSchedule comprueba que la ventana de publicación no esté invertida.
+func validateSchedule(video *models.Video) error {
+	if video.PublishAt != nil && video.ExpireAt != nil && !video.ExpireAt.After(*video.PublishAt) {
+		return errors.New("'expire_at' debe ser posterior a 'publish_at'")
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"streamvault/internal/subtitles"
+)
+
+// HandleSearchVideos busca una frase en las transcripciones, el título y la descripción de los
+// videos. Cada resultado incluye los momentos exactos (en segundos) donde se dice la frase,
+// para que el reproductor pueda saltar directamente a ellos.
+func (h *handler) HandleSearchVideos(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len([]rune(q)) < 2 {
+		respondWithError(w, http.StatusBadRequest, "La búsqueda debe tener al menos 2 caracteres")
+		return
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 || limit > 50 {
+		limit = 20
+	}
+	// Los videos que no puede ver se filtran en la consulta, antes del límite.
+	results, err := h.app.Store.SearchVideos(q, limit, !isAdmin(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al realizar la búsqueda")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+// indexExistingSubtitles indexa las pistas de subtítulos que se guardaron antes de que existiera
+// la búsqueda en transcripciones. Solo tiene trabajo la primera vez que se ejecuta.
+func (a *App) indexExistingSubtitles() error {
+	tracks, err := a.Store.GetUnindexedSubtitleTracks()
+	if err != nil {
+		return err
+	}
+	for _, track := range tracks {
+		cues, err := subtitles.ParseVTT(track.Content)
+		if err != nil {
+			log.Printf("[Video ID: %d] No se pudieron indexar los subtítulos '%s': %v", track.VideoID, track.Lang, err)
+			continue
+		}
+		if err := a.Store.IndexSubtitleCues(track.VideoID, track.Lang, transcriptCues(track.Lang, cues)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"streamvault/internal/media"
+	"streamvault/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// Alcances de los enlaces firmados: cada enlace sirve solo para la operación con la que se emitió.
+const (
+	scopeUpload   = "upload"
+	scopeDownload = "download"
+)
+
+const (
+	// defaultSignedURLExpiry es la validez de un enlace si la petición no indica otra.
+	defaultSignedURLExpiry = time.Hour
+	// maxSignedURLExpiry es la validez máxima que se puede pedir para un enlace.
+	maxSignedURLExpiry = 7 * 24 * time.Hour
+)
+
+// signedURLRequest es el cuerpo de las peticiones que emiten enlaces firmados. MaxSize y
+// ContentType solo se usan en los enlaces de subida.
+type signedURLRequest struct {
+	// ExpiresIn es la validez del enlace en segundos.
+	ExpiresIn   int64  `json:"expires_in"`
+	MaxSize     int64  `json:"max_size"`
+	ContentType string `json:"content_type"`
+}
+
+// signedURLResponse describe un enlace firmado recién emitido.
+type signedURLResponse struct {
+	URL         string    `json:"url"`
+	Method      string    `json:"method"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	MaxSize     int64     `json:"max_size,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+}
+
+// urlSigningKey deriva de JWT_SECRET la clave de los enlaces firmados, para que una firma de
+// enlace no sirva como firma de un token ni al revés.
+func (a *App) urlSigningKey() []byte {
+	mac := hmac.New(sha256.New, []byte(a.JwtSecret))
+	mac.Write([]byte("streamvault signed urls"))
+	return mac.Sum(nil)
+}
+
+// urlSignature firma la ruta y los parámetros del enlace (sin 'sig'). La ruta forma parte de la
+// firma, así que un enlace no se puede usar en otra ruta ni para otro video.
+func (a *App) urlSignature(path string, params url.Values) string {
+	signed := url.Values{}
+	for key, values := range params {
+		if key != "sig" {
+			signed[key] = values
+		}
+	}
+	mac := hmac.New(sha256.New, a.urlSigningKey())
+	mac.Write([]byte(path + "?" + signed.Encode()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signURL arma un enlace firmado para 'path' con el alcance y la validez indicados.
+func (a *App) signURL(path, scope string, expiresAt time.Time, params url.Values) string {
+	params.Set("scope", scope)
+	params.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	params.Set("sig", a.urlSignature(path, params))
+	return path + "?" + params.Encode()
+}
+
+// verifySignedURL comprueba la firma, el alcance y la validez del enlace con el que se hizo la
+// petición. Devuelve sus parámetros, o false si ya respondió con el error.
+func (h *handler) verifySignedURL(w http.ResponseWriter, r *http.Request, scope string) (url.Values, bool) {
+	params := r.URL.Query()
+	expected := h.app.urlSignature(r.URL.Path, params)
+	if !hmac.Equal([]byte(params.Get("sig")), []byte(expected)) {
+		respondWithError(w, http.StatusForbidden, "La firma del enlace no es válida")
+		return nil, false
+	}
+	if params.Get("scope") != scope {
+		respondWithError(w, http.StatusForbidden, "El enlace no sirve para esta operación")
+		return nil, false
+	}
+	expires, err := strconv.ParseInt(params.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		respondWithError(w, http.StatusForbidden, "El enlace venció")
+		return nil, false
+	}
+	return params, true
+}
+
+// decodeSignedURLRequest lee el cuerpo (opcional) de una petición de enlace firmado y calcula
+// su vencimiento. Devuelve false si ya respondió con el error.
+func decodeSignedURLRequest(w http.ResponseWriter, r *http.Request) (*signedURLRequest, time.Time, bool) {
+	var req signedURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Request inválido")
+		return nil, time.Time{}, false
+	}
+	expiry := defaultSignedURLExpiry
+	if req.ExpiresIn != 0 {
+		expiry = time.Duration(req.ExpiresIn) * time.Second
+	}
+	if expiry <= 0 || expiry > maxSignedURLExpiry {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("'expires_in' debe estar entre 1 y %d segundos", int64(maxSignedURLExpiry.Seconds())))
+		return nil, time.Time{}, false
+	}
+	return &req, time.Now().Add(expiry), true
+}
+
+// HandleCreateUploadURL emite un enlace firmado para subir un solo video, de hasta 'max_size'
+// bytes y del tipo 'content_type', sin enviar el token. El video queda a nombre de quien pidió el enlace.
+// El enlace no lleva el rol: al usarlo se vuelve a leer el usuario, para que un cambio de rol o
+// una baja se apliquen aunque el enlace siga vigente.
+func (h *handler) HandleCreateUploadURL(w http.ResponseWriter, r *http.Request) {
+	claims, ok := claimsFromContext(r)
+	if !ok || claims.UserID == 0 {
+		respondWithError(w, http.StatusUnauthorized, "Sesión inválida, vuelve a iniciar sesión")
+		return
+	}
+	req, expiresAt, ok := decodeSignedURLRequest(w, r)
+	if !ok {
+		return
+	}
+	if !media.IsSupportedType(req.ContentType) {
+		respondWithError(w, http.StatusBadRequest, "'content_type' debe ser el tipo de un contenedor admitido (ej: video/mp4)")
+		return
+	}
+	if maxSize := h.app.maxUploadSize(); req.MaxSize <= 0 || req.MaxSize > maxSize {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("'max_size' debe estar entre 1 y %d bytes", maxSize))
+		return
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al crear el enlace")
+		return
+	}
+	params := url.Values{
+		"uid":      {strconv.Itoa(claims.UserID)},
+		"max_size": {strconv.FormatInt(req.MaxSize, 10)},
+		"type":     {req.ContentType},
+		"nonce":    {hex.EncodeToString(nonce)},
+	}
+	respondWithJSON(w, http.StatusCreated, signedURLResponse{
+		URL:         h.app.signURL("/api/signed/upload", scopeUpload, expiresAt, params),
+		Method:      http.MethodPost,
+		ExpiresAt:   expiresAt.UTC().Truncate(time.Second),
+		MaxSize:     req.MaxSize,
+		ContentType: req.ContentType,
+	})
+}
+
+// HandleCreateDownloadURL emite un enlace firmado para descargar el archivo de un video.
+func (h *handler) HandleCreateDownloadURL(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	if _, err := h.app.Store.GetVideoByID(id); err != nil {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	_, expiresAt, ok := decodeSignedURLRequest(w, r)
+	if !ok {
+		return
+	}
+	path := fmt.Sprintf("/api/signed/videos/%d/download", id)
+	respondWithJSON(w, http.StatusCreated, signedURLResponse{
+		URL:       h.app.signURL(path, scopeDownload, expiresAt, url.Values{}),
+		Method:    http.MethodGet,
+		ExpiresAt: expiresAt.UTC().Truncate(time.Second),
+	})
+}
+
+// HandleSignedUpload recibe un video con un enlace de subida firmado. El formulario es el mismo
+// que el de /api/admin/upload. Cada enlace sirve para un solo archivo: si la subida falla se
+// puede reintentar con el mismo enlace mientras no venza.
+func (h *handler) HandleSignedUpload(w http.ResponseWriter, r *http.Request) {
+	params, ok := h.verifySignedURL(w, r, scopeUpload)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(params.Get("uid"))
+	maxSize, sizeErr := strconv.ParseInt(params.Get("max_size"), 10, 64)
+	expires, _ := strconv.ParseInt(params.Get("expires"), 10, 64)
+	nonce := params.Get("nonce")
+	if err != nil || sizeErr != nil || nonce == "" {
+		respondWithError(w, http.StatusBadRequest, "El enlace de subida está incompleto")
+		return
+	}
+	user, err := h.app.Store.GetUserByID(userID)
+	if err != nil {
+		respondWithError(w, http.StatusForbidden, "El usuario que emitió el enlace ya no existe")
+		return
+	}
+	claimed, err := h.app.Store.ClaimUploadURL(nonce, time.Unix(expires, 0))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al verificar el enlace")
+		return
+	}
+	if !claimed {
+		respondWithError(w, http.StatusConflict, "El enlace ya se usó para subir un archivo")
+		return
+	}
+	// La subida se hace en nombre de quien emitió el enlace, con su cuota y su rol actuales.
+	claims := &models.Claims{UserID: user.ID, Role: user.Role}
+	limits := uploadLimits{maxSize: min(maxSize, h.app.maxUploadSize()), mimeType: params.Get("type")}
+	if !h.receiveUpload(w, withClaims(r, claims), claims, limits) {
+		if err := h.app.Store.ReleaseUploadURL(nonce); err != nil {
+			log.Printf("Error al liberar el enlace de subida %s: %v", nonce, err)
+		}
+	}
+}
+
+// HandleSignedDownload entrega el archivo de un video con un enlace de descarga firmado.
+func (h *handler) HandleSignedDownload(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.verifySignedURL(w, r, scopeDownload); !ok {
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	video, err := h.app.Store.GetVideoByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	if video.MimeType != "" {
+		w.Header().Set("Content-Type", video.MimeType)
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": originalFileName(video.FilePath),
+	}))
+	http.ServeFile(w, r, h.app.videoFilePath(video))
+}
+
+// originalFileName quita el prefijo que agrega uniqueFileName para recuperar el nombre con el
+// que se subió el archivo.
+func originalFileName(fileName string) string {
+	prefix, rest, ok := strings.Cut(fileName, "_")
+	if _, err := strconv.ParseInt(prefix, 10, 64); ok && err == nil && rest != "" {
+		return rest
+	}
+	return fileName
+}
+
+// purgeUsedUploadURLs borra el registro de los enlaces de subida usados que ya vencieron.
+func (a *App) purgeUsedUploadURLs() error {
+	n, err := a.Store.PurgeUsedUploadURLs(time.Now())
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("Se borraron %d enlaces de subida vencidos", n)
+	}
+	return nil
+}
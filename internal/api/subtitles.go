@@ -0,0 +1,171 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"streamvault/internal/models"
+	"streamvault/internal/subtitles"
+
+	"github.com/gorilla/mux"
+)
+
+// maxSubtitleSize es el tamaño máximo de un archivo de subtítulos. Incluso una película larga
+// ocupa unos pocos cientos de KB, así que 2MB deja margen de sobra.
+const maxSubtitleSize = 2 << 20
+
+// langRe valida códigos de idioma al estilo BCP 47 (ej: "es", "en-US", "pt-BR").
+var langRe = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// subtitleURL es la ruta pública desde la que el reproductor descarga una pista.
+func subtitleURL(videoID int, lang string) string {
+	return fmt.Sprintf("/api/videos/%d/subtitles/%s.vtt", videoID, lang)
+}
+
+// transcriptCues convierte los cues al formato que se indexa para la búsqueda en transcripciones.
+func transcriptCues(lang string, cues []subtitles.Cue) []models.TranscriptCue {
+	result := make([]models.TranscriptCue, 0, len(cues))
+	for _, cue := range cues {
+		result = append(result, models.TranscriptCue{
+			Lang:  lang,
+			Start: cue.Start.Seconds(),
+			End:   cue.End.Seconds(),
+			Text:  subtitles.PlainText(cue.Text),
+		})
+	}
+	return result
+}
+
+// attachSubtitles completa la lista de pistas del video para la respuesta de detalle.
+func (h *handler) attachSubtitles(video *models.Video) error {
+	tracks, err := h.app.Store.GetSubtitleTracks(video.ID)
+	if err != nil {
+		return err
+	}
+	for i := range tracks {
+		tracks[i].URL = subtitleURL(video.ID, tracks[i].Lang)
+	}
+	video.Subtitles = tracks
+	return nil
+}
+
+// HandleUploadSubtitle agrega o reemplaza la pista de subtítulos de un idioma.
+// Acepta SRT o WebVTT en el campo 'subtitle', junto con 'lang' y 'label' (ej: "es", "Español").
+// El archivo se valida y se guarda siempre convertido a WebVTT.
+func (h *handler) HandleUploadSubtitle(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	video, err := h.app.Store.GetVideoByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	if !canEditVideo(r, video) {
+		respondWithError(w, http.StatusForbidden, "Solo puedes editar tus propios videos")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxSubtitleSize+64<<10)
+	if err := r.ParseMultipartForm(maxSubtitleSize); err != nil {
+		if tooLarge(err) {
+			respondWithError(w, http.StatusRequestEntityTooLarge, "El archivo de subtítulos es demasiado grande (límite 2MB)")
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, "Petición inválida: el cuerpo debe ser un formulario multipart")
+		return
+	}
+	lang := r.FormValue("lang")
+	label := r.FormValue("label")
+	if !langRe.MatchString(lang) {
+		respondWithError(w, http.StatusBadRequest, "Idioma inválido. Usa un código como 'es' o 'en-US'.")
+		return
+	}
+	if label == "" {
+		label = lang
+	}
+	file, _, err := r.FormFile("subtitle")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Petición inválida: Falta el archivo con la clave 'subtitle'")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error interno al leer el archivo")
+		return
+	}
+	cues, err := subtitles.Parse(data)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Subtítulos inválidos: "+err.Error())
+		return
+	}
+	track := &models.SubtitleTrack{
+		VideoID:  id,
+		Lang:     lang,
+		Label:    label,
+		CueCount: len(cues),
+		Content:  subtitles.FormatVTT(cues),
+		URL:      subtitleURL(id, lang),
+	}
+	if err := h.app.Store.SaveSubtitleTrack(track, transcriptCues(lang, cues)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al guardar los subtítulos")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, track)
+}
+
+// HandleGetSubtitle sirve una pista de subtítulos en formato WebVTT para el elemento <track>.
+func (h *handler) HandleGetSubtitle(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	video, err := h.app.Store.GetVideoByID(id)
+	if err != nil || !canSeeVideo(r, video) {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	track, err := h.app.Store.GetSubtitleTrack(id, vars["lang"])
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Subtítulo no encontrado")
+		return
+	}
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	w.Write([]byte(track.Content))
+}
+
+// HandleDeleteSubtitle elimina la pista de subtítulos de un idioma.
+func (h *handler) HandleDeleteSubtitle(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	video, err := h.app.Store.GetVideoByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Video no encontrado")
+		return
+	}
+	if !canEditVideo(r, video) {
+		respondWithError(w, http.StatusForbidden, "Solo puedes editar tus propios videos")
+		return
+	}
+	deleted, err := h.app.Store.DeleteSubtitleTrack(id, vars["lang"])
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al eliminar los subtítulos")
+		return
+	}
+	if !deleted {
+		respondWithError(w, http.StatusNotFound, "Subtítulo no encontrado")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Subtítulos eliminados exitosamente"})
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultTrashRetention se usa si la App no configura TrashRetention.
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// HandleListTrash devuelve los videos que están en la papelera (solo para admins).
+func (h *handler) HandleListTrash(w http.ResponseWriter, r *http.Request) {
+	videos, err := h.app.Store.GetTrashedVideos()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "No se pudo obtener la papelera")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, videos)
+}
+
+// HandleRestoreVideo saca un video de la papelera y lo devuelve con sus datos actuales.
+func (h *handler) HandleRestoreVideo(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "ID de video inválido")
+		return
+	}
+	if err := h.app.Store.RestoreVideo(id); err != nil {
+		respondWithError(w, http.StatusNotFound, "El video no está en la papelera")
+		return
+	}
+	video, err := h.app.Store.GetVideoByID(id)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al obtener el video restaurado")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, video)
+}
+
+// purgeTrash borra definitivamente los videos que superaron el tiempo de retención en la papelera.
+// Los archivos se eliminan después de borrar las filas; si alguno falla, solo se registra.
+func (a *App) purgeTrash() error {
+	retention := a.TrashRetention
+	if retention <= 0 {
+		retention = defaultTrashRetention
+	}
+	videos, err := a.Store.PurgeTrashedVideos(time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+	for _, video := range videos {
+		a.removePoster(video.ID)
+		// Los archivos de una biblioteca referenciada no son de StreamVault y nunca se borran.
+		if video.SourcePath != "" {
+			continue
+		}
+		// El archivo puede estar compartido con otro video creado como duplicado.
+		referenced, err := a.Store.IsFileReferenced(video.FilePath)
+		if err != nil {
+			log.Printf("[Video ID: %d] No se pudo comprobar si el archivo sigue en uso: %v", video.ID, err)
+			continue
+		}
+		if referenced {
+			continue
+		}
+		if err := os.Remove(filepath.Join(a.UploadDir, video.FilePath)); err != nil && !os.IsNotExist(err) {
+			log.Printf("[Video ID: %d] Error al borrar el archivo purgado: %v", video.ID, err)
+		}
+	}
+	if len(videos) > 0 {
+		log.Printf("Papelera: %d videos eliminados definitivamente", len(videos))
+	}
+	return nil
+}
@@ -0,0 +1,445 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"streamvault/internal/fsutil"
+	"streamvault/internal/media"
+	"streamvault/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// Subidas reanudables con el protocolo tus 1.0 (https://tus.io/protocols/resumable-upload),
+// con las extensiones creation, termination y expiration. Cada subida en curso se guarda en
+// UPLOAD_DIR/.tus como dos archivos: los datos recibidos (.bin) y su descripción (.json).
+// El tamaño del .bin es el desplazamiento actual, así que las subidas sobreviven a un reinicio.
+
+const (
+	tusVersion = "1.0.0"
+	// tusExtensions son las extensiones del protocolo que se implementan.
+	tusExtensions = "creation,termination,expiration"
+	// tusBasePath es la ruta de las subidas reanudables.
+	tusBasePath = "/api/admin/uploads"
+	// tusExpiry es cuánto tiempo sin actividad se conserva una subida incompleta.
+	tusExpiry = 24 * time.Hour
+)
+
+// tusUpload es la descripción de una subida reanudable que se guarda junto a sus datos.
+type tusUpload struct {
+	ID         string            `json:"id"`
+	Length     int64             `json:"length"`
+	Metadata   map[string]string `json:"metadata"`
+	UploaderID int               `json:"uploader_id"`
+	ExpiresAt  time.Time         `json:"expires_at"`
+	// VideoID se completa al terminar la subida y crear el video.
+	VideoID int `json:"video_id,omitempty"`
+}
+
+// tusLocks evita que dos peticiones escriban a la vez en la misma subida.
+var tusLocks sync.Map
+
+func lockUpload(id string) func() {
+	mu, _ := tusLocks.LoadOrStore(id, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+func (a *App) tusDir() string { return filepath.Join(a.UploadDir, ".tus") }
+
+func (a *App) tusDataPath(id string) string { return filepath.Join(a.tusDir(), id+".bin") }
+
+func (a *App) tusInfoPath(id string) string { return filepath.Join(a.tusDir(), id+".json") }
+
+func (a *App) loadUpload(id string) (*tusUpload, error) {
+	data, err := os.ReadFile(a.tusInfoPath(id))
+	if err != nil {
+		return nil, err
+	}
+	var upload tusUpload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// saveUpload escribe la descripción de forma atómica, para que un corte nunca deje un .json a medias.
+func (a *App) saveUpload(upload *tusUpload) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFile(a.tusInfoPath(upload.ID), data, 0644)
+}
+
+func (a *App) removeUpload(id string) {
+	os.Remove(a.tusDataPath(id))
+	os.Remove(a.tusInfoPath(id))
+}
+
+// uploadOffset es la cantidad de bytes recibidos hasta ahora.
+func (a *App) uploadOffset(id string) (int64, error) {
+	info, err := os.Stat(a.tusDataPath(id))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// parseTusMetadata decodifica Upload-Metadata: pares "clave valor-en-base64" separados por comas.
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("valor inválido para '%s'", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+func encodeTusMetadata(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for key, value := range metadata {
+		pairs = append(pairs, key+" "+base64.StdEncoding.EncodeToString([]byte(value)))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// tusMiddleware agrega Tus-Resumable a todas las respuestas y rechaza las peticiones de otra
+// versión del protocolo.
+func tusMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", tusVersion)
+		if r.Header.Get("Tus-Resumable") != tusVersion {
+			w.Header().Set("Tus-Version", tusVersion)
+			respondWithError(w, http.StatusPreconditionFailed, "Versión de tus no soportada, usa "+tusVersion)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// tusOptionsMiddleware responde las peticiones OPTIONS a las rutas de tus con las capacidades del
+// servidor (versión, extensiones y tamaño máximo), sin pedir token. Va antes del middleware de CORS,
+// que de otro modo las respondería: si es una consulta previa de CORS se le pasa a él con los
+// encabezados de tus ya puestos.
+func (h *handler) tusOptionsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions || (r.URL.Path != tusBasePath && !strings.HasPrefix(r.URL.Path, tusBasePath+"/")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Tus-Resumable", tusVersion)
+		w.Header().Set("Tus-Version", tusVersion)
+		w.Header().Set("Tus-Extension", tusExtensions)
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.app.maxUploadSize(), 10))
+		if r.Header.Get("Access-Control-Request-Method") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// HandleTusCreate crea una subida reanudable. Los metadatos del video (title, category,
+// description, tags, publish_at, expire_at, filename y on_duplicate) llegan en Upload-Metadata
+// y se validan ahora, para no descubrir un error después de transferir varios GB.
+func (h *handler) HandleTusCreate(w http.ResponseWriter, r *http.Request) {
+	claims, _ := claimsFromContext(r)
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		respondWithError(w, http.StatusBadRequest, "Falta el encabezado Upload-Length o no es válido")
+		return
+	}
+	if maxSize := h.app.maxUploadSize(); length > maxSize {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(maxSize, 10))
+		respondWithError(w, http.StatusRequestEntityTooLarge, "El archivo supera el tamaño máximo permitido")
+		return
+	}
+	metadata, err := parseTusMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Upload-Metadata inválido: "+err.Error())
+		return
+	}
+	field := func(key string) string { return metadata[key] }
+	if _, msg := videoFromUploadFields(field); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if !h.checkQuotaOrRespond(w, claims, length) {
+		return
+	}
+
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al crear la subida")
+		return
+	}
+	upload := &tusUpload{
+		ID:         hex.EncodeToString(idBytes),
+		Length:     length,
+		Metadata:   metadata,
+		UploaderID: claims.UserID,
+		ExpiresAt:  time.Now().Add(tusExpiry),
+	}
+	if err := os.MkdirAll(h.app.tusDir(), 0755); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al crear la subida")
+		return
+	}
+	if err := os.WriteFile(h.app.tusDataPath(upload.ID), nil, 0644); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al crear la subida")
+		return
+	}
+	if err := h.app.saveUpload(upload); err != nil {
+		h.app.removeUpload(upload.ID)
+		respondWithError(w, http.StatusInternalServerError, "Error al crear la subida")
+		return
+	}
+	w.Header().Set("Location", tusBasePath+"/"+upload.ID)
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	// Un archivo vacío queda completo en el momento de crearlo.
+	if length == 0 {
+		h.finishTusUpload(w, r, upload)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// HandleTusHead informa cuántos bytes se recibieron, para que el cliente sepa desde dónde seguir.
+func (h *handler) HandleTusHead(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["uploadId"]
+	upload, err := h.app.loadUpload(id)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	offset := upload.Length
+	if upload.VideoID == 0 {
+		if offset, err = h.app.uploadOffset(id); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	} else {
+		w.Header().Set("Video-Id", strconv.Itoa(upload.VideoID))
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	w.Header().Set("Upload-Metadata", encodeTusMetadata(upload.Metadata))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleTusPatch agrega un tramo de datos en el desplazamiento indicado por Upload-Offset.
+// Si la conexión se corta, lo recibido hasta ese momento se conserva. Cuando llega el último
+// byte, se crea el video.
+func (h *handler) HandleTusPatch(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["uploadId"]
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		respondWithError(w, http.StatusUnsupportedMediaType, "El Content-Type debe ser application/offset+octet-stream")
+		return
+	}
+	unlock := lockUpload(id)
+	defer unlock()
+	upload, err := h.app.loadUpload(id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Subida no encontrada")
+		return
+	}
+	if upload.VideoID != 0 {
+		respondWithError(w, http.StatusConflict, "La subida ya está completa")
+		return
+	}
+	offset, err := h.app.uploadOffset(id)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Subida no encontrada")
+		return
+	}
+	clientOffset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || clientOffset != offset {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+		respondWithError(w, http.StatusConflict, "Upload-Offset no coincide con los bytes recibidos")
+		return
+	}
+
+	f, err := os.OpenFile(h.app.tusDataPath(id), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error al abrir la subida")
+		return
+	}
+	written, copyErr := io.Copy(f, io.LimitReader(r.Body, upload.Length-offset))
+	syncErr := f.Sync()
+	f.Close()
+	offset += written
+	upload.ExpiresAt = time.Now().Add(tusExpiry)
+	if err := h.app.saveUpload(upload); err != nil {
+		log.Printf("[Subida %s] Error al guardar el estado: %v", id, err)
+	}
+	if copyErr != nil || syncErr != nil {
+		// El cliente consultará con HEAD cuántos bytes quedaron y seguirá desde ahí.
+		respondWithError(w, http.StatusInternalServerError, "La transferencia se interrumpió")
+		return
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	if offset < upload.Length {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	h.finishTusUpload(w, r, upload)
+}
+
+// finishTusUpload mueve los datos completos a UPLOAD_DIR y crea el video, igual que una subida
+// por formulario. El video queda a nombre de quien creó la subida, con su cuota y su rol
+// actuales, aunque el último tramo lo envíe otro admin. La descripción de la subida se conserva
+// con el ID del video hasta que vence, para que un HEAD posterior pueda informarlo.
+func (h *handler) finishTusUpload(w http.ResponseWriter, r *http.Request, upload *tusUpload) {
+	uploader, err := h.app.Store.GetUserByID(upload.UploaderID)
+	if err != nil {
+		h.app.removeUpload(upload.ID)
+		respondWithError(w, http.StatusForbidden, "El usuario que inició la subida ya no existe")
+		return
+	}
+	claims := &models.Claims{UserID: uploader.ID, Role: uploader.Role}
+	r = withClaims(r, claims)
+	video, msg := videoFromUploadFields(func(key string) string { return upload.Metadata[key] })
+	if msg != "" {
+		h.app.removeUpload(upload.ID)
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	// El contenedor se valida al final: los primeros tramos pueden ser más cortos que lo necesario.
+	mimeType, err := media.SniffFile(h.app.tusDataPath(upload.ID))
+	if errors.Is(err, media.ErrUnsupportedContainer) {
+		h.app.removeUpload(upload.ID)
+		respondWithError(w, http.StatusUnsupportedMediaType, unsupportedVideoMessage)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error interno al procesar el archivo")
+		return
+	}
+	video.MimeType = mimeType
+	// Se pueden empezar varias subidas reanudables dentro de la cuota; al terminar cada una se
+	// vuelve a comprobar con lo que ya se creó mientras tanto.
+	if !h.checkQuotaOrRespond(w, claims, upload.Length) {
+		h.app.removeUpload(upload.ID)
+		return
+	}
+	hash, err := hashFile(h.app.tusDataPath(upload.ID))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error interno al procesar el archivo")
+		return
+	}
+	filename := upload.Metadata["filename"]
+	if filename == "" {
+		filename = upload.ID
+	}
+	fileName := uniqueFileName(filename)
+	// Los datos ya están sincronizados con el disco (cada PATCH hace fsync), así que basta con moverlos.
+	if err := fsutil.Rename(h.app.tusDataPath(upload.ID), filepath.Join(h.app.UploadDir, fileName)); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error interno al guardar el archivo")
+		return
+	}
+	linkDuplicate := upload.Metadata["on_duplicate"] == "link"
+	if !h.registerUploadedVideo(w, r, claims, video, fileName, upload.Length, hash, linkDuplicate) {
+		os.Remove(h.app.tusInfoPath(upload.ID))
+		return
+	}
+	upload.VideoID = video.ID
+	upload.ExpiresAt = time.Now().Add(tusExpiry)
+	if err := h.app.saveUpload(upload); err != nil {
+		log.Printf("[Subida %s] Error al guardar el estado: %v", upload.ID, err)
+	}
+	w.Header().Set("Video-Id", strconv.Itoa(video.ID))
+	if r.Method == http.MethodPost {
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleTusDelete cancela una subida y borra lo recibido (extensión termination).
+func (h *handler) HandleTusDelete(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["uploadId"]
+	unlock := lockUpload(id)
+	defer unlock()
+	if _, err := h.app.loadUpload(id); err != nil {
+		respondWithError(w, http.StatusNotFound, "Subida no encontrada")
+		return
+	}
+	h.app.removeUpload(id)
+	tusLocks.Delete(id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// expireUploads borra las subidas sin actividad durante más de tusExpiry, y las descripciones
+// de las subidas ya terminadas una vez que vencen.
+func (a *App) expireUploads() error {
+	entries, err := os.ReadDir(a.tusDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	expired := 0
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		unlock := lockUpload(id)
+		upload, err := a.loadUpload(id)
+		isExpired := err == nil && now.After(upload.ExpiresAt)
+		if isExpired {
+			a.removeUpload(id)
+			expired++
+		}
+		unlock()
+		if isExpired {
+			tusLocks.Delete(id)
+		}
+	}
+	// Los datos sin descripción quedan de una creación interrumpida. Se espera a que sean
+	// viejos para no borrar los de una subida que se está creando en este momento.
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".bin")
+		if !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) < tusExpiry {
+			continue
+		}
+		if _, err := os.Stat(a.tusInfoPath(id)); errors.Is(err, os.ErrNotExist) {
+			os.Remove(a.tusDataPath(id))
+		}
+	}
+	if expired > 0 {
+		log.Printf("Subidas reanudables: %d vencidas eliminadas", expired)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"streamvault/internal/fsutil"
+	"streamvault/internal/media"
+)
+
+// defaultMaxUploadSize se usa si la App no configura MaxUploadSize.
+const defaultMaxUploadSize = 10 << 30
+
+const (
+	// maxUploadFieldsSize limita lo que ocupan en total los campos de texto del formulario de subida,
+	// que sí se guardan en memoria.
+	maxUploadFieldsSize = 1 << 20
+	// uploadFormOverhead es lo que se admite en la petición además del video: los campos de texto,
+	// la portada y las cabeceras de cada parte.
+	uploadFormOverhead = maxUploadFieldsSize + maxPosterSize + 64<<10
+)
+
+// unsupportedVideoMessage es la respuesta para un archivo que no es de un contenedor admitido.
+const unsupportedVideoMessage = "Formato de video no admitido: se aceptan MP4/MOV, WebM/MKV, MPEG-TS y Ogg"
+
+// errUploadTooLarge indica que el video supera el tamaño máximo de subida.
+var errUploadTooLarge = errors.New("el archivo supera el tamaño máximo permitido")
+
+// errUnexpectedType indica que el contenedor del video no es el que permite la subida.
+var errUnexpectedType = errors.New("el tipo del archivo no coincide con el permitido")
+
+// maxUploadSize es el tamaño máximo de un video subido, por formulario o por subida reanudable.
+func (a *App) maxUploadSize() int64 {
+	if a.MaxUploadSize > 0 {
+		return a.MaxUploadSize
+	}
+	return defaultMaxUploadSize
+}
+
+// uploadLimits son las restricciones que se aplican al video de una subida por formulario.
+type uploadLimits struct {
+	maxSize int64
+	// mimeType, si no está vacío, es el único tipo de contenedor aceptado.
+	mimeType string
+}
+
+// uploadForm es el contenido de un formulario de subida ya leído. El video está guardado en
+// UPLOAD_DIR con el nombre 'fileName'; el resto de los campos queda en memoria.
+type uploadForm struct {
+	fields   map[string]string
+	poster   image.Image
+	fileName string
+	size     int64
+	hash     string
+	mimeType string
+}
+
+// discard borra el archivo del video, para cuando la subida se rechaza después de recibirlo.
+func (f *uploadForm) discard(uploadDir string) {
+	if f.fileName != "" {
+		os.Remove(filepath.Join(uploadDir, f.fileName))
+	}
+}
+
+// readUploadForm recorre el formulario multipart parte por parte, en el orden en que llegue.
+// La parte 'video' se escribe directamente en disco mientras se calcula su hash, sin pasar
+// por memoria ni por los temporales de ParseMultipartForm. Si algo falla borra lo que haya escrito y devuelve el
+// código y el mensaje de error para el cliente.
+func (h *handler) readUploadForm(r *http.Request, limits uploadLimits) (*uploadForm, int, string) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, http.StatusBadRequest, "La petición debe ser multipart/form-data"
+	}
+	form := &uploadForm{fields: make(map[string]string)}
+	fieldsLeft := int64(maxUploadFieldsSize)
+	fail := func(status int, msg string) (*uploadForm, int, string) {
+		form.discard(h.app.UploadDir)
+		return nil, status, msg
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(limits.readError(err, http.StatusBadRequest, "Formulario multipart inválido"))
+		}
+		switch name := part.FormName(); {
+		case name == "video":
+			if form.fileName != "" {
+				return fail(http.StatusBadRequest, "El formulario solo puede incluir un archivo 'video'")
+			}
+			err := h.receiveVideoPart(part, form, limits)
+			switch {
+			case errors.Is(err, media.ErrUnsupportedContainer):
+				return fail(http.StatusUnsupportedMediaType, unsupportedVideoMessage)
+			case errors.Is(err, errUnexpectedType):
+				return fail(http.StatusUnsupportedMediaType,
+					fmt.Sprintf("El archivo es de tipo %s, pero la subida solo admite %s", form.mimeType, limits.mimeType))
+			case err != nil:
+				return fail(limits.readError(err, http.StatusInternalServerError, "Error interno al guardar el archivo"))
+			}
+		case name == "poster":
+			// La portada es opcional; se valida antes de guardar el video para no dejarlo a medias.
+			poster, status, err := readPoster(part)
+			if err != nil {
+				return fail(limits.readError(err, status, err.Error()))
+			}
+			form.poster = poster
+		case part.FileName() != "":
+			// Otros archivos no se usan: se descartan sin guardarlos.
+			if _, err := io.Copy(io.Discard, part); err != nil {
+				return fail(limits.readError(err, http.StatusBadRequest, "Formulario multipart inválido"))
+			}
+		default:
+			value, err := io.ReadAll(io.LimitReader(part, fieldsLeft+1))
+			if err != nil {
+				return fail(limits.readError(err, http.StatusBadRequest, "Formulario multipart inválido"))
+			}
+			fieldsLeft -= int64(len(value))
+			if fieldsLeft < 0 {
+				return fail(http.StatusRequestEntityTooLarge, "Los campos del formulario son demasiado grandes")
+			}
+			// Como en FormValue, si un campo se repite vale el primero.
+			if _, ok := form.fields[name]; !ok {
+				form.fields[name] = string(value)
+			}
+		}
+		part.Close()
+	}
+	if form.fileName == "" {
+		return fail(http.StatusBadRequest, "Petición inválida: Falta el archivo con la clave 'video'")
+	}
+	return form, 0, ""
+}
+
+// receiveVideoPart guarda la parte 'video' en UPLOAD_DIR con un nombre único. El hash se calcula
+// mientras se escribe, sin volver a leer el archivo. Antes de crear el archivo se identifica el
+// contenedor por sus primeros bytes, así que un formato no admitido no llega a escribirse.
+func (h *handler) receiveVideoPart(part *multipart.Part, form *uploadForm, limits uploadLimits) error {
+	body := bufio.NewReaderSize(part, media.SniffLen)
+	head, err := body.Peek(media.SniffLen)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if form.mimeType, err = media.Sniff(head); err != nil {
+		return err
+	}
+	if limits.mimeType != "" && form.mimeType != limits.mimeType {
+		return errUnexpectedType
+	}
+	original := part.FileName()
+	if original == "" {
+		original = "video"
+	}
+	// El archivo se escribe con un nombre temporal y solo toma su nombre final cuando está
+	// completo y sincronizado con el disco; si la subida se corta, el temporal se borra.
+	fileName := uniqueFileName(original)
+	dst, err := fsutil.Create(filepath.Join(h.app.UploadDir, fileName), 0644)
+	if err != nil {
+		return err
+	}
+	defer dst.Abort()
+	hasher := sha256.New()
+	// Se lee un byte de más para distinguir un archivo justo en el límite de uno que lo supera.
+	written, err := io.Copy(io.MultiWriter(dst, hasher), io.LimitReader(body, limits.maxSize+1))
+	if err != nil {
+		return err
+	}
+	if written > limits.maxSize {
+		return errUploadTooLarge
+	}
+	if err := dst.Commit(); err != nil {
+		return err
+	}
+	form.fileName = fileName
+	form.size = written
+	form.hash = hex.EncodeToString(hasher.Sum(nil))
+	return nil
+}
+
+// tooLarge indica si el error se debe a que la petición superó el tamaño máximo.
+func tooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr) || errors.Is(err, errUploadTooLarge)
+}
+
+// readError traduce un error al leer el formulario: 413 si se superó el tamaño máximo,
+// o el código y el mensaje indicados en cualquier otro caso.
+func (l uploadLimits) readError(err error, status int, msg string) (int, string) {
+	if tooLarge(err) {
+		return l.tooLargeError()
+	}
+	return status, msg
+}
+
+// tooLargeError es la respuesta para un video que supera el tamaño máximo.
+func (l uploadLimits) tooLargeError() (int, string) {
+	return http.StatusRequestEntityTooLarge, fmt.Sprintf("El archivo supera el tamaño máximo permitido (%d bytes)", l.maxSize)
+}
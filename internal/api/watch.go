@@ -0,0 +1,164 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"streamvault/internal/fsutil"
+	"streamvault/internal/media"
+	"streamvault/internal/models"
+	"streamvault/internal/watchfolder"
+)
+
+const (
+	// Subcarpetas de la carpeta vigilada a las que se mueven los archivos ya procesados.
+	watchIngestedDir = "ingested"
+	watchFailedDir   = "failed"
+	// defaultWatchInterval se usa si la App no configura WatchInterval.
+	defaultWatchInterval = 30 * time.Second
+	// watchSettleTime es cuánto debe pasar un archivo sin crecer para importarlo.
+	watchSettleTime = 30 * time.Second
+	// defaultWatchCategory es la categoría de los archivos dejados en la raíz de la carpeta
+	// vigilada cuyo sidecar no indica otra.
+	defaultWatchCategory = "General"
+)
+
+// watchFolder vigila WatchDir e importa cada video que aparece en ella.
+func (a *App) watchFolder() {
+	interval := a.WatchInterval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	log.Printf("Vigilando la carpeta %s para importar videos", a.WatchDir)
+	w := &watchfolder.Watcher{
+		Dir:          a.WatchDir,
+		Skip:         []string{watchIngestedDir, watchFailedDir},
+		PollInterval: interval,
+		SettleTime:   watchSettleTime,
+		Accept:       func(name string) bool { return !watchfolder.IsSidecar(name) },
+		Ready:        a.ingestWatchedFile,
+	}
+	w.Run()
+}
+
+// ingestWatchedFile importa un archivo de la carpeta vigilada y lo mueve, junto con su sidecar,
+// a la subcarpeta 'ingested' o, si falló, a 'failed' con un .error.txt que explica el motivo.
+func (a *App) ingestWatchedFile(path string) {
+	rel, _ := filepath.Rel(a.WatchDir, path)
+	_, sidecar, _ := watchfolder.ReadSidecar(path)
+	video, err := a.importWatchedFile(path, rel)
+	target := watchIngestedDir
+	if err != nil {
+		target = watchFailedDir
+		log.Printf("Carpeta vigilada: no se pudo importar %s: %v", rel, err)
+	} else {
+		log.Printf("[Video ID: %d] Importado desde la carpeta vigilada: %s", video.ID, rel)
+	}
+	dest, moveErr := a.moveWatchedFile(path, target)
+	if moveErr != nil {
+		log.Printf("Carpeta vigilada: no se pudo mover %s a '%s': %v", rel, target, moveErr)
+		return
+	}
+	if sidecar != "" {
+		if _, err := a.moveWatchedFile(sidecar, target); err != nil {
+			log.Printf("Carpeta vigilada: no se pudo mover %s a '%s': %v", filepath.Base(sidecar), target, err)
+		}
+	}
+	if err != nil {
+		if err := os.WriteFile(dest+".error.txt", []byte(err.Error()+"\n"), 0644); err != nil {
+			log.Printf("Carpeta vigilada: no se pudo guardar el motivo del error de %s: %v", rel, err)
+		}
+	}
+}
+
+// importWatchedFile crea el video a partir de un archivo de la carpeta vigilada. Los metadatos
+// salen del sidecar; si falta el título se usa el nombre del archivo y, si falta la categoría,
+// el nombre de la carpeta que lo contiene. El archivo se copia a UPLOAD_DIR.
+func (a *App) importWatchedFile(path, rel string) (*models.Video, error) {
+	meta, _, err := watchfolder.ReadSidecar(path)
+	if err != nil {
+		return nil, fmt.Errorf("sidecar inválido: %w", err)
+	}
+	if meta.Title == "" {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		meta.Title = strings.TrimSpace(strings.NewReplacer("_", " ", ".", " ").Replace(name))
+	}
+	if meta.Category == "" {
+		meta.Category = defaultWatchCategory
+		if dir := filepath.Dir(rel); dir != "." {
+			meta.Category = filepath.Base(dir)
+		}
+	}
+	fields := map[string]string{
+		"title": meta.Title, "description": meta.Description, "category": meta.Category,
+		"tags": strings.Join(meta.Tags, ","), "publish_at": meta.PublishAt, "expire_at": meta.ExpireAt,
+	}
+	video, msg := videoFromUploadFields(func(key string) string { return fields[key] })
+	if msg != "" {
+		return nil, fmt.Errorf("metadatos inválidos: %s", msg)
+	}
+	if video.MimeType, err = media.SniffFile(path); err != nil {
+		return nil, err
+	}
+	fileName, size, hash, err := a.copyToUploadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo copiar el archivo: %w", err)
+	}
+	duplicate, err := a.addUploadedVideo(video, fileName, size, hash, 0, nil, false)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo guardar el video: %w", err)
+	}
+	if duplicate != nil {
+		return nil, fmt.Errorf("el archivo ya existe como el video %d (%q)", duplicate.ID, duplicate.Title)
+	}
+	return video, nil
+}
+
+// copyToUploadDir copia un archivo a UPLOAD_DIR con un nombre único, de forma atómica y
+// calculando su hash mientras lo copia.
+func (a *App) copyToUploadDir(path string) (fileName string, size int64, hash string, err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", 0, "", err
+	}
+	defer src.Close()
+	fileName = uniqueFileName(filepath.Base(path))
+	dst, err := fsutil.Create(filepath.Join(a.UploadDir, fileName), 0644)
+	if err != nil {
+		return "", 0, "", err
+	}
+	defer dst.Abort()
+	hasher := sha256.New()
+	if size, err = io.Copy(io.MultiWriter(dst, hasher), src); err != nil {
+		return "", 0, "", err
+	}
+	if err := dst.Commit(); err != nil {
+		return "", 0, "", err
+	}
+	return fileName, size, hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// moveWatchedFile mueve un archivo de la carpeta vigilada a la subcarpeta 'target', conservando
+// su ruta relativa. Si ya hay un archivo con ese nombre, agrega la fecha para no pisarlo.
+func (a *App) moveWatchedFile(path, target string) (string, error) {
+	rel, err := filepath.Rel(a.WatchDir, path)
+	if err != nil {
+		return "", err
+	}
+	dest := filepath.Join(a.WatchDir, target, rel)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(dest); err == nil {
+		ext := filepath.Ext(dest)
+		dest = fmt.Sprintf("%s.%s%s", strings.TrimSuffix(dest, ext), time.Now().Format("20060102-150405"), ext)
+	}
+	return dest, os.Rename(path, dest)
+}
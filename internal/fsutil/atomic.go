@@ -0,0 +1,103 @@
+// Package fsutil escribe archivos de forma atómica: el contenido se escribe en un archivo
+// temporal del mismo directorio, se sincroniza con el disco y recién entonces se renombra al
+// nombre final. Así, después de un corte, el archivo final está completo o no existe.
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tempMarker forma parte del nombre de todos los archivos temporales de este paquete.
+const tempMarker = ".tmp-"
+
+// File es un archivo en escritura que aparece con su nombre final solo al llamar a Commit.
+type File struct {
+	*os.File
+	path string
+	perm os.FileMode
+	done bool
+}
+
+// Create empieza a escribir el archivo 'path'. Hay que terminar con Commit o con Abort.
+func Create(path string, perm os.FileMode) (*File, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+tempMarker+"*")
+	if err != nil {
+		return nil, err
+	}
+	return &File{File: tmp, path: path, perm: perm}, nil
+}
+
+// Commit sincroniza el contenido con el disco y le da al archivo su nombre final. Si falla,
+// el temporal se borra y el archivo final queda como estaba.
+func (f *File) Commit() error {
+	if f.done {
+		return os.ErrClosed
+	}
+	f.done = true
+	err := f.Chmod(f.perm)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), f.path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	// El rename solo es definitivo cuando se sincroniza el directorio.
+	return SyncDir(filepath.Dir(f.path))
+}
+
+// Abort descarta lo escrito. Se puede llamar con defer: después de Commit no hace nada.
+func (f *File) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// WriteFile es os.WriteFile, pero atómico.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	f, err := Create(path, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// Rename mueve un archivo ya sincronizado y sincroniza el directorio de destino.
+func Rename(oldPath, newPath string) error {
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	return SyncDir(filepath.Dir(newPath))
+}
+
+// SyncDir sincroniza un directorio con el disco, para que los archivos creados o renombrados
+// en él sobrevivan a un corte.
+func SyncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// IsTemp indica si 'name' es el nombre de un temporal de este paquete, que puede haber quedado
+// a medias si el proceso se cortó mientras escribía.
+func IsTemp(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.Contains(name, tempMarker)
+}
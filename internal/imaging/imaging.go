@@ -0,0 +1,144 @@
+// Package imaging valida y redimensiona las imágenes de portada de los videos usando solo
+// la biblioteca estándar.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// MaxPixels limita el tamaño de las imágenes que se decodifican, para que una imagen pequeña
+// en disco pero enorme en memoria (una "bomba de descompresión") no agote la RAM.
+const MaxPixels = 25_000_000
+
+// ErrUnsupportedFormat indica que los primeros bytes no corresponden a un formato aceptado.
+var ErrUnsupportedFormat = errors.New("formato de imagen no soportado (se aceptan JPEG, PNG y GIF)")
+
+// Variant es un tamaño de portada que se genera a partir de la imagen original.
+type Variant struct {
+	Name  string
+	Width int
+}
+
+// Variants son los tamaños que se generan, de mayor a menor.
+var Variants = []Variant{
+	{Name: "large", Width: 1280},
+	{Name: "medium", Width: 640},
+	{Name: "small", Width: 320},
+}
+
+// Sniff identifica el formato de una imagen por sus primeros bytes ("magic bytes").
+// No confía en la extensión del archivo ni en el Content-Type enviado por el cliente.
+func Sniff(head []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		return "jpeg", nil
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return "png", nil
+	case bytes.HasPrefix(head, []byte("GIF87a")), bytes.HasPrefix(head, []byte("GIF89a")):
+		return "gif", nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Decode valida el formato y las dimensiones de la imagen y la decodifica.
+// En los GIF animados solo se usa el primer cuadro.
+func Decode(data []byte) (image.Image, error) {
+	format, err := Sniff(data)
+	if err != nil {
+		return nil, err
+	}
+	var decodeConfig func(io.Reader) (image.Config, error)
+	var decode func(io.Reader) (image.Image, error)
+	switch format {
+	case "jpeg":
+		decodeConfig, decode = jpeg.DecodeConfig, jpeg.Decode
+	case "png":
+		decodeConfig, decode = png.DecodeConfig, png.Decode
+	case "gif":
+		decodeConfig, decode = gif.DecodeConfig, gif.Decode
+	}
+	cfg, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("imagen %s dañada: %w", format, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, fmt.Errorf("la imagen mide %dx%d píxeles, el máximo es %d píxeles en total", cfg.Width, cfg.Height, MaxPixels)
+	}
+	img, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("imagen %s dañada: %w", format, err)
+	}
+	return img, nil
+}
+
+// Resize reduce la imagen al ancho indicado manteniendo la proporción. Cada píxel de destino
+// es el promedio del área de origen que cubre, lo que evita el "aliasing" al reducir mucho.
+// Las imágenes más angostas que 'width' no se agrandan.
+func Resize(src image.Image, width int) *image.RGBA {
+	rgba := toRGBA(src)
+	sw, sh := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+	if width >= sw {
+		return rgba
+	}
+	height := sh * width / sw
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for dy := 0; dy < height; dy++ {
+		y0, y1 := span(dy, height, sh)
+		for dx := 0; dx < width; dx++ {
+			x0, x1 := span(dx, width, sw)
+			var r, g, b, a, n uint32
+			for y := y0; y < y1; y++ {
+				row := rgba.Pix[y*rgba.Stride:]
+				for x := x0; x < x1; x++ {
+					p := row[x*4 : x*4+4]
+					r += uint32(p[0])
+					g += uint32(p[1])
+					b += uint32(p[2])
+					a += uint32(p[3])
+					n++
+				}
+			}
+			o := dst.PixOffset(dx, dy)
+			dst.Pix[o] = uint8(r / n)
+			dst.Pix[o+1] = uint8(g / n)
+			dst.Pix[o+2] = uint8(b / n)
+			dst.Pix[o+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// span devuelve el rango [from, to) de píxeles de origen que cubre el píxel de destino i.
+func span(i, dstSize, srcSize int) (from, to int) {
+	from = i * srcSize / dstSize
+	to = (i + 1) * srcSize / dstSize
+	if to <= from {
+		to = from + 1
+	}
+	return from, to
+}
+
+// toRGBA copia la imagen sobre un fondo blanco, ya que JPEG no admite transparencia.
+func toRGBA(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Over)
+	return dst
+}
+
+// EncodeJPEG escribe la imagen como JPEG con una calidad adecuada para portadas.
+func EncodeJPEG(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+}
@@ -0,0 +1,150 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"streamvault/internal/fsutil"
+)
+
+// maxMoovSize limita el tamaño del box 'moov' que se carga en memoria para reescribirlo.
+// Incluso las grabaciones de varias horas rara vez superan unas decenas de MB.
+const maxMoovSize = 256 << 20
+
+// ErrCannotFastStart indica que el archivo tiene 'moov' al final pero no se puede reordenar
+// sin riesgo (por ejemplo, 'moov' comprimido o desplazamientos de 32 bits que se desbordarían).
+var ErrCannotFastStart = errors.New("no se puede mover 'moov' al principio del archivo")
+
+// FastStart reescribe un MP4/MOV para que 'moov' quede antes de 'mdat', de modo que el navegador
+// pueda empezar a reproducirlo sin descargar primero el final del archivo. Los desplazamientos
+// de las tablas 'stco'/'co64' se corrigen, el resultado se escribe en un archivo temporal y luego
+// reemplaza al original con un rename atómico. Devuelve false si el archivo ya estaba en orden.
+func FastStart(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	top, err := readBoxes(f, 0, stat.Size())
+	if err != nil {
+		return false, err
+	}
+	moovIdx, mdatIdx := -1, -1
+	for i, b := range top {
+		switch {
+		case b.typ == "moov" && moovIdx == -1:
+			moovIdx = i
+		case b.typ == "mdat" && mdatIdx == -1:
+			mdatIdx = i
+		}
+	}
+	if moovIdx == -1 {
+		return false, errors.New("falta el box 'moov'")
+	}
+	if mdatIdx == -1 || moovIdx < mdatIdx {
+		return false, nil
+	}
+	moov, mdat := top[moovIdx], top[mdatIdx]
+	if moov.size > maxMoovSize {
+		return false, fmt.Errorf("%w: 'moov' ocupa %d bytes", ErrCannotFastStart, moov.size)
+	}
+	moovData := make([]byte, moov.size)
+	if _, err := f.ReadAt(moovData, moov.offset); err != nil {
+		return false, errTruncated("box 'moov'", moov.offset)
+	}
+	// Todo lo que está entre el primer 'mdat' y 'moov' se desplaza hacia adelante lo que ocupa 'moov'.
+	if err := shiftChunkOffsets(moovData, moov.headerSize, mdat.offset, moov.offset, moov.size); err != nil {
+		return false, err
+	}
+
+	tmp, err := fsutil.Create(path, stat.Mode().Perm())
+	if err != nil {
+		return false, err
+	}
+	defer tmp.Abort()
+	sections := []io.Reader{
+		io.NewSectionReader(f, 0, mdat.offset),
+		bytes.NewReader(moovData),
+		io.NewSectionReader(f, mdat.offset, moov.offset-mdat.offset),
+		io.NewSectionReader(f, moov.offset+moov.size, stat.Size()-moov.offset-moov.size),
+	}
+	if _, err := io.Copy(tmp, io.MultiReader(sections...)); err != nil {
+		return false, err
+	}
+	if err := tmp.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// shiftChunkOffsets suma 'delta' a las entradas de 'stco' y 'co64' que apuntan al rango [from, to).
+// 'moovData' es el box 'moov' completo y se modifica en el lugar; sus hijos empiezan después de
+// la cabecera, que ocupa 'headerSize' bytes (8, o 16 si usa un tamaño de 64 bits).
+func shiftChunkOffsets(moovData []byte, headerSize, from, to, delta int64) error {
+	r := bytes.NewReader(moovData)
+	var walk func(start, end int64) error
+	walk = func(start, end int64) error {
+		boxes, err := readBoxes(r, start, end)
+		if err != nil {
+			return err
+		}
+		for _, b := range boxes {
+			switch b.typ {
+			case "cmov":
+				return fmt.Errorf("%w: 'moov' comprimido", ErrCannotFastStart)
+			case "trak", "mdia", "minf", "stbl":
+				if err := walk(b.dataOffset(), b.offset+b.size); err != nil {
+					return err
+				}
+			case "stco", "co64":
+				if err := shiftTable(moovData[b.dataOffset():b.offset+b.size], b.typ == "co64", from, to, delta); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return walk(headerSize, int64(len(moovData)))
+}
+
+// shiftTable corrige una tabla de desplazamientos: versión y flags (4 bytes), cantidad de
+// entradas (4 bytes) y luego las entradas de 4 bytes ('stco') u 8 bytes ('co64').
+func shiftTable(data []byte, wide bool, from, to, delta int64) error {
+	if len(data) < 8 {
+		return errors.New("tabla de desplazamientos truncada")
+	}
+	count := int64(binary.BigEndian.Uint32(data[4:8]))
+	entrySize := int64(4)
+	if wide {
+		entrySize = 8
+	}
+	if count*entrySize > int64(len(data))-8 {
+		return errors.New("tabla de desplazamientos truncada")
+	}
+	for i := int64(0); i < count; i++ {
+		entry := data[8+i*entrySize : 8+(i+1)*entrySize]
+		if wide {
+			if v := int64(binary.BigEndian.Uint64(entry)); v >= from && v < to {
+				binary.BigEndian.PutUint64(entry, uint64(v+delta))
+			}
+			continue
+		}
+		v := int64(binary.BigEndian.Uint32(entry))
+		if v < from || v >= to {
+			continue
+		}
+		if v+delta > 0xFFFFFFFF {
+			return fmt.Errorf("%w: un desplazamiento no cabe en 'stco'", ErrCannotFastStart)
+		}
+		binary.BigEndian.PutUint32(entry, uint32(v+delta))
+	}
+	return nil
+}
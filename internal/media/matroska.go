@@ -0,0 +1,309 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// ebmlMagic es el ID del elemento de cabecera EBML con el que empiezan los archivos WebM/Matroska.
+var ebmlMagic = []byte{0x1A, 0x45, 0xDF, 0xA3}
+
+// matroskaEpoch es el origen de las fechas en Matroska: 1 de enero de 2001 (UTC).
+var matroskaEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// IDs de los elementos EBML que se leen.
+const (
+	idEBML           = 0x1A45DFA3
+	idDocType        = 0x4282
+	idSegment        = 0x18538067
+	idInfo           = 0x1549A966
+	idTimestampScale = 0x2AD7B1
+	idDuration       = 0x4489
+	idDateUTC        = 0x4461
+	idTracks         = 0x1654AE6B
+	idTrackEntry     = 0xAE
+	idTrackType      = 0x83
+	idCodecID        = 0x86
+	idVideo          = 0xE0
+	idPixelWidth     = 0xB0
+	idPixelHeight    = 0xBA
+	idCluster        = 0x1F43B675
+)
+
+// unknownSize marca un elemento cuyo tamaño no se declaró (habitual en transmisiones en vivo).
+const unknownSize = -1
+
+// element es un elemento EBML: su ID y la posición de su contenido.
+type element struct {
+	id         uint32
+	dataOffset int64
+	size       int64
+}
+
+// end es la posición siguiente al último byte del elemento, limitada a 'limit' si su tamaño es desconocido.
+func (e element) end(limit int64) int64 {
+	if e.size == unknownSize {
+		return limit
+	}
+	return e.dataOffset + e.size
+}
+
+// readVint lee un entero de longitud variable de EBML. Con 'keepMarker' se conserva el bit que
+// indica la longitud, como se hace con los IDs.
+func readVint(r io.ReaderAt, offset int64, keepMarker bool) (value uint64, length int, allOnes bool, err error) {
+	var buf [8]byte
+	if _, err := r.ReadAt(buf[:1], offset); err != nil {
+		return 0, 0, false, errTruncated("elemento EBML", offset)
+	}
+	first := buf[0]
+	if first == 0 {
+		return 0, 0, false, fmt.Errorf("entero EBML inválido en el byte %d", offset)
+	}
+	length = 1
+	for mask := byte(0x80); first&mask == 0; mask >>= 1 {
+		length++
+	}
+	if length > 1 {
+		if _, err := r.ReadAt(buf[1:length], offset+1); err != nil {
+			return 0, 0, false, errTruncated("elemento EBML", offset)
+		}
+	}
+	if !keepMarker {
+		buf[0] &^= 0x80 >> (length - 1)
+	}
+	for i := 0; i < length; i++ {
+		value = value<<8 | uint64(buf[i])
+	}
+	// Un tamaño con todos los bits de datos en 1 significa "desconocido".
+	allOnes = value == (uint64(1)<<(7*length))-1
+	return value, length, allOnes, nil
+}
+
+// readElement lee la cabecera (ID y tamaño) del elemento que empieza en 'offset'.
+func readElement(r io.ReaderAt, offset, limit int64) (element, error) {
+	id, idLen, _, err := readVint(r, offset, true)
+	if err != nil {
+		return element{}, err
+	}
+	if idLen > 4 {
+		return element{}, fmt.Errorf("ID EBML inválido en el byte %d", offset)
+	}
+	size, sizeLen, unknown, err := readVint(r, offset+int64(idLen), false)
+	if err != nil {
+		return element{}, err
+	}
+	e := element{id: uint32(id), dataOffset: offset + int64(idLen) + int64(sizeLen), size: int64(size)}
+	if unknown {
+		e.size = unknownSize
+	} else if e.size < 0 || e.dataOffset+e.size > limit {
+		return element{}, errTruncated(fmt.Sprintf("elemento EBML 0x%X", e.id), offset)
+	}
+	return e, nil
+}
+
+// readChildren lista los elementos contenidos en 'parent'.
+func readChildren(r io.ReaderAt, parent element, limit int64) ([]element, error) {
+	var children []element
+	end := parent.end(limit)
+	for offset := parent.dataOffset; offset < end; {
+		e, err := readElement(r, offset, end)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, e)
+		if e.size == unknownSize {
+			break
+		}
+		offset = e.dataOffset + e.size
+	}
+	return children, nil
+}
+
+// readData lee el contenido de un elemento simple (números, textos), que siempre es pequeño.
+func readData(r io.ReaderAt, e element) ([]byte, error) {
+	if e.size == unknownSize || e.size > 1<<10 {
+		return nil, fmt.Errorf("elemento EBML 0x%X demasiado grande", e.id)
+	}
+	data := make([]byte, e.size)
+	if _, err := r.ReadAt(data, e.dataOffset); err != nil {
+		return nil, errTruncated(fmt.Sprintf("elemento EBML 0x%X", e.id), e.dataOffset)
+	}
+	return data, nil
+}
+
+func readUint(r io.ReaderAt, e element) (uint64, error) {
+	data, err := readData(r, e)
+	if err != nil || len(data) > 8 {
+		return 0, errors.New("entero EBML inválido")
+	}
+	var v uint64
+	for _, b := range data {
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
+
+func readFloat(r io.ReaderAt, e element) (float64, error) {
+	data, err := readData(r, e)
+	if err != nil {
+		return 0, err
+	}
+	switch len(data) {
+	case 0:
+		return 0, nil
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	}
+	return 0, errors.New("número de punto flotante EBML inválido")
+}
+
+func readString(r io.ReaderAt, e element) (string, error) {
+	data, err := readData(r, e)
+	return strings.TrimRight(string(data), "\x00"), err
+}
+
+func probeMatroska(r io.ReaderAt, size int64) (*Info, error) {
+	header, err := readElement(r, 0, size)
+	if err != nil {
+		return nil, err
+	}
+	if header.id != idEBML || header.size == unknownSize {
+		return nil, errors.New("cabecera EBML inválida")
+	}
+	info := &Info{Container: "matroska"}
+	fields, err := readChildren(r, header, size)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		if f.id == idDocType {
+			if docType, err := readString(r, f); err == nil && docType == "webm" {
+				info.Container = "webm"
+			}
+		}
+	}
+
+	segment, err := readElement(r, header.end(size), size)
+	if err != nil {
+		return nil, err
+	}
+	if segment.id != idSegment {
+		return nil, errors.New("falta el elemento Segment")
+	}
+	// Los elementos del segmento se recorren uno a uno: los Cluster (los datos) se saltan y,
+	// una vez leídos Info y Tracks, no hace falta seguir.
+	var haveInfo, haveTracks bool
+	end := segment.end(size)
+	for offset := segment.dataOffset; offset < end && !(haveInfo && haveTracks); {
+		e, err := readElement(r, offset, end)
+		if err != nil {
+			return nil, err
+		}
+		switch e.id {
+		case idInfo:
+			if err := probeMatroskaInfo(r, e, size, info); err != nil {
+				return nil, err
+			}
+			haveInfo = true
+		case idTracks:
+			if err := probeMatroskaTracks(r, e, size, info); err != nil {
+				return nil, err
+			}
+			haveTracks = true
+		}
+		if e.size == unknownSize {
+			break
+		}
+		offset = e.dataOffset + e.size
+	}
+	if !haveTracks {
+		return nil, errors.New("falta el elemento Tracks con la descripción de las pistas")
+	}
+	return info, nil
+}
+
+func probeMatroskaInfo(r io.ReaderAt, infoElem element, size int64, info *Info) error {
+	fields, err := readChildren(r, infoElem, size)
+	if err != nil {
+		return err
+	}
+	scale := uint64(1000000) // Valor por defecto: milisegundos.
+	var duration float64
+	for _, f := range fields {
+		switch f.id {
+		case idTimestampScale:
+			if v, err := readUint(r, f); err == nil && v > 0 {
+				scale = v
+			}
+		case idDuration:
+			if duration, err = readFloat(r, f); err != nil {
+				return err
+			}
+		case idDateUTC:
+			if v, err := readUint(r, f); err == nil {
+				t := matroskaEpoch.Add(time.Duration(int64(v)))
+				info.CreatedAt = &t
+			}
+		}
+	}
+	if duration > 0 && !math.IsInf(duration, 0) {
+		info.Duration = time.Duration(duration * float64(scale))
+	}
+	return nil
+}
+
+func probeMatroskaTracks(r io.ReaderAt, tracks element, size int64, info *Info) error {
+	entries, err := readChildren(r, tracks, size)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.id != idTrackEntry {
+			continue
+		}
+		fields, err := readChildren(r, entry, size)
+		if err != nil {
+			return err
+		}
+		var trackType uint64
+		var codec string
+		var width, height uint64
+		for _, f := range fields {
+			switch f.id {
+			case idTrackType:
+				trackType, _ = readUint(r, f)
+			case idCodecID:
+				codec, _ = readString(r, f)
+			case idVideo:
+				video, err := readChildren(r, f, size)
+				if err != nil {
+					return err
+				}
+				for _, v := range video {
+					switch v.id {
+					case idPixelWidth:
+						width, _ = readUint(r, v)
+					case idPixelHeight:
+						height, _ = readUint(r, v)
+					}
+				}
+			}
+		}
+		// Tipos de pista de Matroska: 1 = video, 2 = audio.
+		switch {
+		case trackType == 1 && info.VideoCodec == "":
+			info.VideoCodec = codec
+			info.Width, info.Height = int(width), int(height)
+		case trackType == 2 && info.AudioCodec == "":
+			info.AudioCodec = codec
+		}
+	}
+	return nil
+}
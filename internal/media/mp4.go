@@ -0,0 +1,252 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// mp4Epoch es el origen de las fechas en MP4/MOV: 1 de enero de 1904 (UTC).
+var mp4Epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// isTopLevelBox reconoce los tipos de box con los que suele empezar un archivo MP4/MOV.
+// Los MOV antiguos no tienen 'ftyp' y empiezan directamente con otros boxes.
+func isTopLevelBox(typ string) bool {
+	switch typ {
+	case "ftyp", "moov", "mdat", "wide", "free", "skip", "pnot":
+		return true
+	}
+	return false
+}
+
+// box es un box (o "atom") de un archivo MP4/MOV: su tipo y la posición de su contenido.
+type box struct {
+	typ string
+	// offset es la posición del inicio del box (incluida la cabecera) y size su tamaño total.
+	offset int64
+	size   int64
+	// headerSize es 8, o 16 si el box usa un tamaño de 64 bits.
+	headerSize int64
+}
+
+// dataOffset es la posición donde empieza el contenido del box.
+func (b box) dataOffset() int64 { return b.offset + b.headerSize }
+
+// dataSize es el tamaño del contenido del box, sin la cabecera.
+func (b box) dataSize() int64 { return b.size - b.headerSize }
+
+// readBoxes lista los boxes contenidos entre 'start' y 'end'.
+func readBoxes(r io.ReaderAt, start, end int64) ([]box, error) {
+	var boxes []box
+	for offset := start; offset+8 <= end; {
+		var hdr [16]byte
+		if _, err := r.ReadAt(hdr[:8], offset); err != nil {
+			return nil, errTruncated("box", offset)
+		}
+		b := box{typ: string(hdr[4:8]), offset: offset, size: int64(binary.BigEndian.Uint32(hdr[:4])), headerSize: 8}
+		switch b.size {
+		case 0:
+			// Un tamaño 0 significa "hasta el final del archivo" (solo en el último box).
+			b.size = end - offset
+		case 1:
+			if _, err := r.ReadAt(hdr[8:16], offset+8); err != nil {
+				return nil, errTruncated("box", offset)
+			}
+			b.size = int64(binary.BigEndian.Uint64(hdr[8:16]))
+			b.headerSize = 16
+		}
+		if b.size < b.headerSize || offset+b.size > end {
+			return nil, fmt.Errorf("box '%s' con tamaño inválido en el byte %d", printableType(b.typ), offset)
+		}
+		boxes = append(boxes, b)
+		offset += b.size
+	}
+	return boxes, nil
+}
+
+// printableType evita que un tipo de box binario ensucie los mensajes de error.
+func printableType(typ string) string {
+	for _, c := range typ {
+		if c < 0x20 || c > 0x7e {
+			return fmt.Sprintf("%x", typ)
+		}
+	}
+	return typ
+}
+
+// findBox devuelve el primer box del tipo indicado.
+func findBox(boxes []box, typ string) (box, bool) {
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b, true
+		}
+	}
+	return box{}, false
+}
+
+// readBoxData lee el contenido completo de un box. Solo se usa con boxes de cabecera, que son
+// pequeños; 'limit' protege contra archivos que declaran tamaños absurdos.
+func readBoxData(r io.ReaderAt, b box, limit int64) ([]byte, error) {
+	if b.dataSize() > limit {
+		return nil, fmt.Errorf("box '%s' demasiado grande (%d bytes)", b.typ, b.dataSize())
+	}
+	data := make([]byte, b.dataSize())
+	if _, err := r.ReadAt(data, b.dataOffset()); err != nil {
+		return nil, errTruncated("box '"+b.typ+"'", b.offset)
+	}
+	return data, nil
+}
+
+func probeMP4(r io.ReaderAt, size int64) (*Info, error) {
+	top, err := readBoxes(r, 0, size)
+	if err != nil {
+		return nil, err
+	}
+	info := &Info{Container: "mp4"}
+	if ftyp, ok := findBox(top, "ftyp"); ok {
+		data, err := readBoxData(r, ftyp, 1<<10)
+		if err == nil && len(data) >= 4 && string(data[:4]) == "qt  " {
+			info.Container = "mov"
+		}
+	}
+	moov, ok := findBox(top, "moov")
+	if !ok {
+		return nil, errors.New("falta el box 'moov' con la descripción del video")
+	}
+	children, err := readBoxes(r, moov.dataOffset(), moov.offset+moov.size)
+	if err != nil {
+		return nil, err
+	}
+	mvhd, ok := findBox(children, "mvhd")
+	if !ok {
+		return nil, errors.New("falta el box 'mvhd'")
+	}
+	data, err := readBoxData(r, mvhd, 1<<10)
+	if err != nil {
+		return nil, err
+	}
+	created, timescale, duration, err := parseTimeHeader(data)
+	if err != nil {
+		return nil, fmt.Errorf("box 'mvhd' inválido: %w", err)
+	}
+	if timescale > 0 {
+		info.Duration = time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+	}
+	// Muchos programas escriben 0 en lugar de la fecha real.
+	if created > 0 {
+		t := mp4Epoch.Add(time.Duration(created) * time.Second)
+		info.CreatedAt = &t
+	}
+	for _, trak := range children {
+		if trak.typ != "trak" {
+			continue
+		}
+		if err := probeTrack(r, trak, info); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// parseTimeHeader lee los campos comunes de 'mvhd' y 'mdhd' en sus versiones 0 y 1.
+func parseTimeHeader(data []byte) (created uint64, timescale uint32, duration uint64, err error) {
+	if len(data) < 4 {
+		return 0, 0, 0, errors.New("demasiado corto")
+	}
+	switch data[0] {
+	case 0:
+		if len(data) < 20 {
+			return 0, 0, 0, errors.New("demasiado corto")
+		}
+		created = uint64(binary.BigEndian.Uint32(data[4:8]))
+		timescale = binary.BigEndian.Uint32(data[12:16])
+		duration = uint64(binary.BigEndian.Uint32(data[16:20]))
+	case 1:
+		if len(data) < 32 {
+			return 0, 0, 0, errors.New("demasiado corto")
+		}
+		created = binary.BigEndian.Uint64(data[4:12])
+		timescale = binary.BigEndian.Uint32(data[20:24])
+		duration = binary.BigEndian.Uint64(data[24:32])
+	default:
+		return 0, 0, 0, fmt.Errorf("versión %d desconocida", data[0])
+	}
+	return created, timescale, duration, nil
+}
+
+// probeTrack completa la información con la primera pista de video y la primera de audio.
+func probeTrack(r io.ReaderAt, trak box, info *Info) error {
+	children, err := readBoxes(r, trak.dataOffset(), trak.offset+trak.size)
+	if err != nil {
+		return err
+	}
+	mdia, ok := findBox(children, "mdia")
+	if !ok {
+		return nil
+	}
+	mdiaChildren, err := readBoxes(r, mdia.dataOffset(), mdia.offset+mdia.size)
+	if err != nil {
+		return err
+	}
+	hdlr, ok := findBox(mdiaChildren, "hdlr")
+	if !ok {
+		return nil
+	}
+	hdlrData, err := readBoxData(r, hdlr, 1<<12)
+	if err != nil || len(hdlrData) < 12 {
+		return nil
+	}
+	handler := string(hdlrData[8:12])
+	if (handler == "vide" && info.VideoCodec != "") || (handler == "soun" && info.AudioCodec != "") ||
+		(handler != "vide" && handler != "soun") {
+		return nil
+	}
+	codec := sampleEntryType(r, mdiaChildren)
+	if handler == "soun" {
+		info.AudioCodec = codec
+		return nil
+	}
+	info.VideoCodec = codec
+	// Las dimensiones de presentación están al final de 'tkhd', en punto fijo 16.16.
+	if tkhd, ok := findBox(children, "tkhd"); ok {
+		if data, err := readBoxData(r, tkhd, 1<<10); err == nil && len(data) >= 84 {
+			n := len(data)
+			info.Width = int(binary.BigEndian.Uint32(data[n-8:n-4]) >> 16)
+			info.Height = int(binary.BigEndian.Uint32(data[n-4:]) >> 16)
+		}
+	}
+	return nil
+}
+
+// sampleEntryType devuelve el tipo de la primera entrada de 'stsd' (ej: "avc1", "hvc1", "mp4a"),
+// que identifica el códec de la pista.
+func sampleEntryType(r io.ReaderAt, mdiaChildren []box) string {
+	path := []string{"minf", "stbl", "stsd"}
+	boxes := mdiaChildren
+	var current box
+	for _, typ := range path {
+		b, ok := findBox(boxes, typ)
+		if !ok {
+			return ""
+		}
+		current = b
+		if typ != "stsd" {
+			var err error
+			if boxes, err = readBoxes(r, b.dataOffset(), b.offset+b.size); err != nil {
+				return ""
+			}
+		}
+	}
+	// 'stsd' tiene versión, flags y cantidad de entradas (8 bytes) antes de la primera entrada.
+	var hdr [8]byte
+	if current.dataSize() < 16 {
+		return ""
+	}
+	if _, err := r.ReadAt(hdr[:], current.dataOffset()+8); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(printableType(string(hdr[4:8])))
+}
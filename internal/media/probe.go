@@ -0,0 +1,76 @@
+// Package media lee la estructura de los contenedores de video (MP4/MOV y WebM/Matroska)
+// para obtener sus características sin depender de herramientas externas como ffprobe, e
+// identifica el contenedor de un archivo por sus primeros bytes.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrUnknownFormat indica que el archivo no es un contenedor que el paquete sepa leer.
+var ErrUnknownFormat = errors.New("formato de contenedor no reconocido")
+
+// Info son las características de un archivo de video. Los campos que el contenedor
+// no declara quedan en su valor cero.
+type Info struct {
+	Container  string
+	Duration   time.Duration
+	Width      int
+	Height     int
+	VideoCodec string
+	AudioCodec string
+	// Bitrate es la tasa media del archivo completo en bits por segundo.
+	Bitrate   int64
+	CreatedAt *time.Time
+}
+
+// Probe abre el archivo y lee sus características.
+func Probe(path string) (*Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return ProbeReader(f, stat.Size())
+}
+
+// ProbeReader lee las características de un archivo de 'size' bytes. Solo lee las cabeceras:
+// los datos de audio y video se saltan, así que el costo no depende del tamaño del archivo.
+func ProbeReader(r io.ReaderAt, size int64) (*Info, error) {
+	head := make([]byte, 12)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+	var info *Info
+	switch {
+	case bytes.HasPrefix(head, ebmlMagic):
+		info, err = probeMatroska(r, size)
+	case len(head) >= 8 && isTopLevelBox(string(head[4:8])):
+		info, err = probeMP4(r, size)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.Duration > 0 {
+		info.Bitrate = int64(float64(size*8) / info.Duration.Seconds())
+	}
+	return info, nil
+}
+
+// errTruncated indica que una estructura declara un tamaño mayor al que queda en el archivo.
+func errTruncated(what string, offset int64) error {
+	return fmt.Errorf("%s truncado en el byte %d", what, offset)
+}
@@ -0,0 +1,111 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"slices"
+)
+
+// SniffLen es la cantidad de bytes del principio del archivo que usa Sniff.
+const SniffLen = 512
+
+// mpegTSPacketSize es el tamaño de los paquetes de un MPEG-TS; cada uno empieza con tsSyncByte.
+const (
+	mpegTSPacketSize = 188
+	tsSyncByte       = 0x47
+)
+
+// oggMagic es la marca con la que empieza cada página de un archivo Ogg.
+var oggMagic = []byte("OggS")
+
+// ErrUnsupportedContainer indica que el archivo no es de ninguno de los contenedores admitidos.
+var ErrUnsupportedContainer = errors.New("el archivo no es un contenedor de video admitido (MP4/MOV, WebM/MKV, MPEG-TS u Ogg)")
+
+// supportedTypes son los tipos MIME que puede devolver Sniff.
+var supportedTypes = []string{"video/mp4", "video/quicktime", "video/webm", "video/x-matroska", "video/mp2t", "video/ogg"}
+
+// IsSupportedType indica si 'mimeType' es el de uno de los contenedores admitidos.
+func IsSupportedType(mimeType string) bool {
+	return slices.Contains(supportedTypes, mimeType)
+}
+
+// Sniff identifica el contenedor por los primeros bytes del archivo ('head', hasta SniffLen)
+// y devuelve su tipo MIME. No confía en el nombre ni en el tipo que declare el cliente.
+func Sniff(head []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(head, ebmlMagic):
+		return sniffMatroska(head), nil
+	case len(head) >= 8 && isTopLevelBox(string(head[4:8])) && validBoxSize(binary.BigEndian.Uint32(head[:4])):
+		// Los MOV se reconocen por la marca "qt  " de 'ftyp', o porque no tienen 'ftyp'.
+		if string(head[4:8]) != "ftyp" || (len(head) >= 12 && string(head[8:12]) == "qt  ") {
+			return "video/quicktime", nil
+		}
+		return "video/mp4", nil
+	case bytes.HasPrefix(head, oggMagic):
+		return "video/ogg", nil
+	case isMPEGTS(head):
+		return "video/mp2t", nil
+	}
+	return "", ErrUnsupportedContainer
+}
+
+// SniffFile lee el principio del archivo y lo identifica con Sniff.
+func SniffFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	head := make([]byte, SniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return Sniff(head[:n])
+}
+
+// validBoxSize descarta tamaños de box imposibles; 0 ("hasta el final") y 1 (tamaño de 64 bits)
+// son valores especiales válidos.
+func validBoxSize(size uint32) bool {
+	return size == 0 || size == 1 || size >= 8
+}
+
+// sniffMatroska distingue WebM de Matroska por el DocType de la cabecera EBML. Si la cabecera
+// no se puede leer se asume Matroska, que es el formato general.
+func sniffMatroska(head []byte) string {
+	r := bytes.NewReader(head)
+	size := int64(len(head))
+	header, err := readElement(r, 0, size)
+	if err != nil || header.id != idEBML || header.size == unknownSize {
+		return "video/x-matroska"
+	}
+	fields, err := readChildren(r, header, size)
+	if err != nil {
+		return "video/x-matroska"
+	}
+	for _, f := range fields {
+		if f.id == idDocType {
+			if docType, err := readString(r, f); err == nil && docType == "webm" {
+				return "video/webm"
+			}
+		}
+	}
+	return "video/x-matroska"
+}
+
+// isMPEGTS reconoce un MPEG-TS por el byte de sincronización al principio de los primeros
+// paquetes. Con uno solo habría demasiados falsos positivos, así que se exigen al menos dos.
+func isMPEGTS(head []byte) bool {
+	if len(head) <= mpegTSPacketSize {
+		return false
+	}
+	for offset := 0; offset < len(head); offset += mpegTSPacketSize {
+		if head[offset] != tsSyncByte {
+			return false
+		}
+	}
+	return true
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -20,11 +22,412 @@ type Video struct {
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
 	Category    string    `json:"category"`
+	Tags        []string  `json:"tags"`
 	FilePath    string    `json:"file_path"`
+	SizeBytes   int64     `json:"size_bytes"`
 	UploadedAt  time.Time `json:"uploaded_at"`
+	// MimeType es el tipo detectado por el contenido del archivo al subirlo (ej: "video/mp4").
+	// Está vacío en los videos que todavía no se analizaron.
+	MimeType string `json:"mime_type,omitempty"`
+	// SourcePath es la ruta absoluta del archivo cuando el video se importó de una biblioteca sin
+	// copiarlo a UPLOAD_DIR. FilePath sigue siendo el nombre con el que se sirve en /stream.
+	SourcePath string `json:"-"`
+	// ReleaseYear es el año de estreno, si se conoce (por ejemplo, por el NFO de una biblioteca).
+	ReleaseYear *int `json:"release_year,omitempty"`
+	// UploaderID es nil para los videos subidos antes de registrar al autor o cuyo autor se eliminó.
+	UploaderID *int `json:"uploader_id"`
+	// Version aumenta con cada edición y se usa como ETag para detectar ediciones concurrentes.
+	Version int `json:"version"`
+	// ExternalID identifica al video de forma estable entre instalaciones (importación/exportación).
+	ExternalID string `json:"external_id"`
+	// ContentHash es el SHA-256 del archivo en hexadecimal; vacío mientras no se haya calculado.
+	ContentHash string `json:"content_sha256,omitempty"`
+	// Subtitles y Chapters solo se completan en la respuesta de detalle de un video.
+	Subtitles []SubtitleTrack `json:"subtitles,omitempty"`
+	Chapters  []Chapter       `json:"chapters,omitempty"`
+	// Ventana de disponibilidad. Un valor nil significa "sin límite" en ese extremo.
+	PublishAt *time.Time `json:"publish_at"`
+	ExpireAt  *time.Time `json:"expire_at"`
+	// Poster es nil si el video no tiene imagen de portada.
+	Poster *Poster `json:"poster"`
+	// Media describe el archivo (duración, resolución, códecs) según el análisis posterior a la subida.
+	Media MediaInfo `json:"media"`
+	// DeletedAt indica cuándo se movió el video a la papelera (nil si no está en ella).
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
+// MediaInfo son las características técnicas del archivo de un video.
+type MediaInfo struct {
+	// Status es "pending" hasta que se analiza el archivo, "probing" mientras se analiza, y luego
+	// "ok" o "failed".
+	Status          string     `json:"status"`
+	Error           string     `json:"error,omitempty"`
+	Container       string     `json:"container,omitempty"`
+	DurationSeconds float64    `json:"duration_seconds"`
+	Width           int        `json:"width"`
+	Height          int        `json:"height"`
+	VideoCodec      string     `json:"video_codec,omitempty"`
+	AudioCodec      string     `json:"audio_codec,omitempty"`
+	Bitrate         int64      `json:"bitrate"`
+	CreatedAt       *time.Time `json:"created_at,omitempty"`
+}
+
+// Estados posibles de MediaInfo.
+const (
+	MediaStatusPending = "pending"
+	MediaStatusProbing = "probing"
+	MediaStatusOK      = "ok"
+	MediaStatusFailed  = "failed"
+)
+
+// Poster contiene las URLs de las variantes de la imagen de portada de un video.
+type Poster struct {
+	Small     string    `json:"small"`
+	Medium    string    `json:"medium"`
+	Large     string    `json:"large"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewPoster arma las URLs de la portada de un video. La fecha de actualización se agrega
+// como parámetro para que los clientes puedan guardarla en caché sin servir una portada vieja.
+func NewPoster(videoID int, updatedAt time.Time) *Poster {
+	url := func(size string) string {
+		return fmt.Sprintf("/api/videos/%d/poster/%s.jpg?v=%d", videoID, size, updatedAt.Unix())
+	}
+	return &Poster{Small: url("small"), Medium: url("medium"), Large: url("large"), UpdatedAt: updatedAt}
+}
+
+// IsAvailableAt indica si el video está dentro de su ventana de publicación en el instante t.
+func (v *Video) IsAvailableAt(t time.Time) bool {
+	if v.PublishAt != nil && t.Before(*v.PublishAt) {
+		return false
+	}
+	if v.ExpireAt != nil && !t.Before(*v.ExpireAt) {
+		return false
+	}
+	return true
+}
+
+// Quota limita lo que un usuario puede subir. Un campo nil significa "sin límite"
+// (o, en la cuota propia de un usuario, "usar el valor por defecto de su rol").
+type Quota struct {
+	MaxBytes  *int64 `json:"max_bytes"`
+	MaxVideos *int   `json:"max_videos"`
+}
+
+// QuotaUsage muestra el consumo actual de un usuario frente a su cuota efectiva.
+type QuotaUsage struct {
+	UsedBytes  int64 `json:"used_bytes"`
+	UsedVideos int   `json:"used_videos"`
+	Quota
+}
+
+// SubtitleTrack es una pista de subtítulos de un video, guardada siempre en formato WebVTT.
+type SubtitleTrack struct {
+	VideoID   int       `json:"-"`
+	Lang      string    `json:"lang"`
+	Label     string    `json:"label"`
+	CueCount  int       `json:"cue_count"`
+	URL       string    `json:"url"`
+	Content   string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Chapter marca el inicio (en segundos) de una sección del video.
+type Chapter struct {
+	Start        float64 `json:"start"`
+	Title        string  `json:"title"`
+	ThumbnailURL string  `json:"thumbnail_url,omitempty"`
+}
+
+// Origen de los capítulos de un video: editados a mano o leídos de la descripción.
+const (
+	ChapterSourceManual      = "manual"
+	ChapterSourceDescription = "description"
+)
+
+// TranscriptCue es el texto de un cue de subtítulos con su posición en el video, en segundos.
+type TranscriptCue struct {
+	Lang  string  `json:"lang"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// SearchResult es un video encontrado por la búsqueda, con los momentos de la transcripción
+// donde aparece la frase buscada. Matches queda vacío si solo coincidió el título o la descripción.
+type SearchResult struct {
+	Video   *Video          `json:"video"`
+	Matches []TranscriptCue `json:"matches"`
+}
+
+// VideoRevision es una instantánea de los metadatos editables de un video.
+// Changes se calcula al listar, comparando con la revisión anterior.
+type VideoRevision struct {
+	VideoID     int           `json:"video_id"`
+	Rev         int           `json:"rev"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	Category    string        `json:"category"`
+	Tags        []string      `json:"tags"`
+	AuthorID    *int          `json:"author_id"`
+	AuthorName  string        `json:"author_name"`
+	CreatedAt   time.Time     `json:"created_at"`
+	Changes     []FieldChange `json:"changes"`
+}
+
+// FieldChange describe el cambio de un campo entre dos revisiones.
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// VideoFilter selecciona videos para una operación masiva. Los campos vacíos no filtran.
+type VideoFilter struct {
+	Category      string `json:"category"`
+	Tag           string `json:"tag"`
+	UploaderID    *int   `json:"uploader_id"`
+	TitleContains string `json:"title_contains"`
+}
+
+// BulkVideoRequest describe una operación masiva sobre videos, elegidos por IDs o por filtro.
+type BulkVideoRequest struct {
+	IDs        []int        `json:"ids"`
+	Filter     *VideoFilter `json:"filter"`
+	Action     string       `json:"action"`
+	Category   string       `json:"category"`
+	Tags       []string     `json:"tags"`
+	Visibility string       `json:"visibility"`
+	DryRun     bool         `json:"dry_run"`
+}
+
+// UserFilter selecciona usuarios para una operación masiva. Los campos vacíos no filtran.
+type UserFilter struct {
+	Role             string `json:"role"`
+	UsernameContains string `json:"username_contains"`
+	EmailContains    string `json:"email_contains"`
+}
+
+// BulkUserRequest describe una operación masiva sobre usuarios, elegidos por IDs o por filtro.
+type BulkUserRequest struct {
+	IDs    []int       `json:"ids"`
+	Filter *UserFilter `json:"filter"`
+	Action string      `json:"action"`
+	Role   string      `json:"role"`
+	DryRun bool        `json:"dry_run"`
+}
+
+// BulkItemResult es el resultado de una operación masiva para un elemento concreto.
+type BulkItemResult struct {
+	ID      int    `json:"id"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// BulkReport resume una operación masiva. Applied es false si fue una simulación (dry_run)
+// o si algún elemento falló y la transacción completa se revirtió.
+type BulkReport struct {
+	Action  string           `json:"action"`
+	DryRun  bool             `json:"dry_run"`
+	Applied bool             `json:"applied"`
+	Matched int              `json:"matched"`
+	Results []BulkItemResult `json:"results"`
+}
+
+// Estados posibles de BulkItemResult.
+const (
+	BulkStatusOK       = "ok"
+	BulkStatusNotFound = "not_found"
+	BulkStatusSkipped  = "skipped"
+	BulkStatusError    = "error"
+)
+
+// CatalogVideo es la representación portable de un video que se usa al exportar e importar
+// el catálogo. Solo contiene metadatos: el archivo se referencia por nombre, nunca se copia.
+type CatalogVideo struct {
+	ExternalID  string     `json:"external_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Category    string     `json:"category"`
+	Tags        []string   `json:"tags"`
+	FilePath    string     `json:"file_path"`
+	SizeBytes   int64      `json:"size_bytes"`
+	PublishAt   *time.Time `json:"publish_at"`
+	ExpireAt    *time.Time `json:"expire_at"`
+	UploadedAt  *time.Time `json:"uploaded_at,omitempty"`
+	// Provided son los campos que trae el archivo importado (claves del JSON o columnas del CSV).
+	// Al actualizar un video solo se cambian esos; nil significa que vienen todos.
+	Provided map[string]bool `json:"-"`
+}
+
+// UnmarshalJSON lee el video y anota qué claves trae, para distinguir un campo ausente de uno vacío.
+func (v *CatalogVideo) UnmarshalJSON(data []byte) error {
+	type plain CatalogVideo
+	if err := json.Unmarshal(data, (*plain)(v)); err != nil {
+		return err
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+	v.Provided = make(map[string]bool, len(keys))
+	for key := range keys {
+		v.Provided[key] = true
+	}
+	return nil
+}
+
+// Has indica si el archivo importado trae el campo 'field'.
+func (v *CatalogVideo) Has(field string) bool {
+	return v.Provided == nil || v.Provided[field]
+}
+
+// CatalogTerm es una categoría o etiqueta del catálogo con la cantidad de videos que la usan.
+type CatalogTerm struct {
+	Name       string `json:"name"`
+	VideoCount int    `json:"video_count"`
+}
+
+// Catalog es el documento completo que produce la exportación en JSON.
+type Catalog struct {
+	ExportedAt time.Time      `json:"exported_at"`
+	Videos     []CatalogVideo `json:"videos"`
+	Categories []CatalogTerm  `json:"categories"`
+	Tags       []CatalogTerm  `json:"tags"`
+}
+
+// ImportRowResult es el resultado de importar una fila del catálogo. Row empieza en 1 y, en CSV,
+// no cuenta la fila de encabezados.
+type ImportRowResult struct {
+	Row        int    `json:"row"`
+	ExternalID string `json:"external_id"`
+	VideoID    int    `json:"video_id,omitempty"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+}
+
+// ImportReport resume una importación. Si alguna fila tiene errores, no se aplica ninguna.
+type ImportReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Applied bool              `json:"applied"`
+	Results []ImportRowResult `json:"results"`
+}
+
+// Estados posibles de ImportRowResult.
+const (
+	ImportStatusCreated   = "created"
+	ImportStatusUpdated   = "updated"
+	ImportStatusUnchanged = "unchanged"
+	ImportStatusError     = "error"
+)
+
+// LibraryImportItem es el resultado de importar un NFO de una biblioteca. Status usa los mismos
+// valores que ImportRowResult; "unchanged" indica que el archivo ya estaba en el catálogo.
+type LibraryImportItem struct {
+	// NFO y FilePath son relativos a la carpeta importada.
+	NFO      string `json:"nfo"`
+	FilePath string `json:"file_path,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Category string `json:"category,omitempty"`
+	// Year es el año de estreno que se guarda como 'release_year' del video.
+	Year int `json:"year,omitempty"`
+	// RuntimeMinutes es la duración que indica el NFO; es la del video mientras no se analice
+	// el archivo, o si el análisis no la obtiene.
+	RuntimeMinutes int    `json:"runtime_minutes,omitempty"`
+	Poster         string `json:"poster,omitempty"`
+	VideoID        int    `json:"video_id,omitempty"`
+	Status         string `json:"status"`
+	Message        string `json:"message,omitempty"`
+}
+
+// LibraryImportReport resume la importación de una biblioteca. A diferencia del catálogo, cada
+// NFO se importa por separado: un error en uno no impide importar los demás.
+type LibraryImportReport struct {
+	DryRun bool `json:"dry_run"`
+	// Mode es "copy" (los archivos se copian a UPLOAD_DIR) o "reference" (se usan en su lugar).
+	Mode    string              `json:"mode"`
+	Results []LibraryImportItem `json:"results"`
+}
+
+// Modos de importación de una biblioteca.
+const (
+	LibraryModeCopy      = "copy"
+	LibraryModeReference = "reference"
+)
+
+// VideoFile relaciona un video (incluidos los de la papelera) con el archivo que usa.
+type VideoFile struct {
+	VideoID  int    `json:"video_id"`
+	Title    string `json:"title"`
+	FilePath string `json:"file_path"`
+	// SourcePath es la ruta del archivo fuera de UPLOAD_DIR de los videos que lo referencian en su lugar.
+	SourcePath string `json:"source_path,omitempty"`
+	Trashed    bool   `json:"trashed"`
+}
+
+// OrphanFile es un archivo de UPLOAD_DIR que ningún video usa.
+type OrphanFile struct {
+	Name       string    `json:"name"`
+	SizeBytes  int64     `json:"size_bytes"`
+	ModifiedAt time.Time `json:"modified_at"`
+	// Temporary indica que es un temporal que quedó de una escritura interrumpida.
+	Temporary bool `json:"temporary"`
+	// QuarantinedAs es la ruta (relativa a UPLOAD_DIR) a la que se movió, si se movió.
+	QuarantinedAs string `json:"quarantined_as,omitempty"`
+}
+
+// ReconcileReport es el resultado de comparar los archivos de UPLOAD_DIR con los videos registrados.
+type ReconcileReport struct {
+	Quarantine  bool         `json:"quarantine"`
+	OrphanFiles []OrphanFile `json:"orphan_files"`
+	// MissingFiles son los videos cuyo archivo no existe. No se modifican: se informan para revisarlos.
+	MissingFiles []VideoFile `json:"missing_files"`
+}
+
+// CoWatch indica cuántos espectadores distintos vieron ambos videos del par.
+type CoWatch struct {
+	VideoA  int
+	VideoB  int
+	Viewers int
+}
+
+// RelatedScore es la puntuación de un video candidato como recomendación de otro.
+type RelatedScore struct {
+	VideoID int
+	Score   float64
+}
+
+// VideoStatsBucket resume la actividad de un video durante una hora o un día.
+type VideoStatsBucket struct {
+	Start         time.Time `json:"start"`
+	Views         int       `json:"views"`
+	UniqueViewers int       `json:"unique_viewers"`
+	WatchSeconds  float64   `json:"watch_seconds"`
+	AvgCompletion float64   `json:"avg_completion"`
+}
+
+// VideoStats es el informe de analíticas de un video en un rango de fechas.
+type VideoStats struct {
+	VideoID           int                `json:"video_id"`
+	From              time.Time          `json:"from"`
+	To                time.Time          `json:"to"`
+	Views             int                `json:"views"`
+	UniqueViewers     int                `json:"unique_viewers"`
+	TotalWatchSeconds float64            `json:"total_watch_seconds"`
+	AvgCompletion     float64            `json:"avg_completion"`
+	Granularity       string             `json:"granularity"`
+	Buckets           []VideoStatsBucket `json:"buckets"`
+}
+
+// Listas de marcadores integradas que cada usuario tiene disponibles.
+const (
+	BookmarkFavorites  = "favorites"
+	BookmarkWatchLater = "watch_later"
+)
+
 type Claims struct {
+	UserID   int    `json:"user_id"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Role     string `json:"role"`
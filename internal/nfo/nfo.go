@@ -0,0 +1,119 @@
+// Package nfo lee los archivos .nfo con los que Kodi y Jellyfin guardan los metadatos de cada
+// película, serie o episodio de una biblioteca.
+package nfo
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Tipos de NFO, según el elemento raíz del documento.
+const (
+	KindMovie   = "movie"
+	KindEpisode = "episodedetails"
+	KindTVShow  = "tvshow"
+)
+
+// ErrUnknownKind indica que el NFO no es de una película, una serie ni un episodio (por ejemplo,
+// el de un álbum de música).
+var ErrUnknownKind = errors.New("el NFO no es de una película, una serie ni un episodio")
+
+// Info son los metadatos de un NFO. Los campos ausentes quedan vacíos o en cero.
+type Info struct {
+	// Kind es KindMovie, KindEpisode o KindTVShow.
+	Kind string
+	// Title es el título de la película, la serie o el episodio.
+	Title string
+	// ShowTitle es el título de la serie a la que pertenece un episodio.
+	ShowTitle string
+	// Plot es la sinopsis; si el NFO solo trae el resumen corto (<outline>), se usa ese.
+	Plot   string
+	Genres []string
+	// Year es el año de estreno. Si falta <year> se toma de <premiered> o <aired>.
+	Year int
+	// Runtime es la duración en minutos.
+	Runtime int
+	// Season y Episode numeran un episodio; 0 si el NFO no los indica.
+	Season  int
+	Episode int
+}
+
+// document refleja los elementos que se leen de los tres tipos de NFO.
+type document struct {
+	XMLName   xml.Name
+	Title     string   `xml:"title"`
+	ShowTitle string   `xml:"showtitle"`
+	Plot      string   `xml:"plot"`
+	Outline   string   `xml:"outline"`
+	Genres    []string `xml:"genre"`
+	Year      string   `xml:"year"`
+	Premiered string   `xml:"premiered"`
+	Aired     string   `xml:"aired"`
+	Runtime   string   `xml:"runtime"`
+	Season    string   `xml:"season"`
+	Episode   string   `xml:"episode"`
+}
+
+// Parse lee un NFO. Solo se interpreta el primer elemento: lo que sigue (otro <episodedetails>
+// en los archivos con varios episodios, o la URL que algunos NFO llevan al final) se ignora.
+func Parse(r io.Reader) (*Info, error) {
+	decoder := xml.NewDecoder(r)
+	// Muchos NFO declaran otra codificación pero en la práctica son UTF-8 o ASCII.
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
+	var doc document
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("XML inválido: %w", err)
+	}
+	kind := strings.ToLower(doc.XMLName.Local)
+	if kind != KindMovie && kind != KindEpisode && kind != KindTVShow {
+		return nil, ErrUnknownKind
+	}
+	info := &Info{
+		Kind:      kind,
+		Title:     strings.TrimSpace(doc.Title),
+		ShowTitle: strings.TrimSpace(doc.ShowTitle),
+		Plot:      strings.TrimSpace(doc.Plot),
+		Year:      leadingInt(doc.Year),
+		Runtime:   leadingInt(doc.Runtime),
+		Season:    leadingInt(doc.Season),
+		Episode:   leadingInt(doc.Episode),
+	}
+	if info.Plot == "" {
+		info.Plot = strings.TrimSpace(doc.Outline)
+	}
+	// Algunos programas guardan todos los géneros en un solo elemento, separados por " / ".
+	for _, genre := range doc.Genres {
+		for _, g := range strings.Split(genre, "/") {
+			if g = strings.TrimSpace(g); g != "" {
+				info.Genres = append(info.Genres, g)
+			}
+		}
+	}
+	if info.Year == 0 {
+		for _, date := range []string{doc.Premiered, doc.Aired} {
+			if date = strings.TrimSpace(date); len(date) >= 4 {
+				if year := leadingInt(date[:4]); year > 0 {
+					info.Year = year
+					break
+				}
+			}
+		}
+	}
+	return info, nil
+}
+
+// leadingInt lee el número con el que empieza el texto (como en "120 min"), o 0 si no hay ninguno.
+func leadingInt(s string) int {
+	s = strings.TrimSpace(s)
+	end := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) })
+	if end >= 0 {
+		s = s[:end]
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"streamvault/internal/models"
+)
+
+// createVideoViewsTableSQL guarda cada "sesión de visualización". Una sesión agrupa todas las
+// peticiones (incluidas las de tipo Range al hacer seek) y beacons del mismo espectador mientras
+// no pase más tiempo que la ventana de deduplicación entre una y otra. progress_at es la hora del
+// último beacon de la sesión (NULL si todavía no recibió ninguno).
+const createVideoViewsTableSQL = `
+    CREATE TABLE IF NOT EXISTS video_views (
+        id BIGSERIAL PRIMARY KEY,
+        video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+        user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+        viewer_key VARCHAR(100) NOT NULL,
+        started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        watch_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+        max_position DOUBLE PRECISION NOT NULL DEFAULT 0,
+        duration DOUBLE PRECISION NOT NULL DEFAULT 0
+    );
+    CREATE INDEX IF NOT EXISTS video_views_viewer_idx ON video_views (video_id, viewer_key, last_seen_at);
+    CREATE INDEX IF NOT EXISTS video_views_started_idx ON video_views (started_at);
+    ALTER TABLE video_views ADD COLUMN IF NOT EXISTS progress_at TIMESTAMP WITH TIME ZONE;`
+
+// Las tablas de agregados tienen la misma forma; solo cambia el tamaño del intervalo.
+const createVideoStatsTablesSQL = `
+    CREATE TABLE IF NOT EXISTS video_stats_hourly (
+        video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+        bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+        views INTEGER NOT NULL,
+        unique_viewers INTEGER NOT NULL,
+        watch_seconds DOUBLE PRECISION NOT NULL,
+        completion_sum DOUBLE PRECISION NOT NULL,
+        completion_count INTEGER NOT NULL,
+        PRIMARY KEY (video_id, bucket)
+    );
+    CREATE TABLE IF NOT EXISTS video_stats_daily (
+        video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+        bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+        views INTEGER NOT NULL,
+        unique_viewers INTEGER NOT NULL,
+        watch_seconds DOUBLE PRECISION NOT NULL,
+        completion_sum DOUBLE PRECISION NOT NULL,
+        completion_count INTEGER NOT NULL,
+        PRIMARY KEY (video_id, bucket)
+    );`
+
+// statsTables relaciona cada granularidad con su tabla y la unidad de date_trunc.
+var statsTables = map[string]struct{ table, unit string }{
+	"hour": {"video_stats_hourly", "hour"},
+	"day":  {"video_stats_daily", "day"},
+}
+
+// RecordView registra una visualización. Si el mismo espectador tiene una sesión abierta
+// dentro de la ventana, solo se extiende esa sesión en lugar de contar una vista nueva.
+// Al empezar la reproducción el navegador suele enviar varias peticiones Range a la vez; un lock
+// por (video, espectador) hace que solo la primera cree la sesión y las demás la extiendan.
+func (s *PostgresStore) RecordView(videoID, userID int, viewerKey string, window time.Duration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, fmt.Sprintf("video_views:%d:%s", videoID, viewerKey)); err != nil {
+		return err
+	}
+	query := `
+    WITH recent AS (
+        UPDATE video_views SET last_seen_at = NOW()
+        WHERE id = (
+            SELECT id FROM video_views
+            WHERE video_id = $1 AND viewer_key = $2 AND last_seen_at > NOW() - $3::float8 * INTERVAL '1 second'
+            ORDER BY last_seen_at DESC LIMIT 1
+        )
+        RETURNING id
+    )
+    INSERT INTO video_views (video_id, user_id, viewer_key)
+    SELECT $1::integer, $4::integer, $2::varchar WHERE NOT EXISTS (SELECT 1 FROM recent)`
+	if _, err := tx.Exec(query, videoID, viewerKey, window.Seconds(), nullableID(userID)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ErrNoViewSession indica que el espectador no tiene una sesión de visualización abierta del video.
+var ErrNoViewSession = errors.New("no hay una sesión de visualización abierta")
+
+// ErrWatchTimeExceeded indica que un beacon declara más tiempo visto que el transcurrido desde el
+// beacon anterior de la misma sesión (o desde que empezó, si es el primero).
+var ErrWatchTimeExceeded = errors.New("el tiempo visto supera el tiempo transcurrido")
+
+// beaconClockSlack es el margen que se tolera entre el tiempo que declara el reproductor y el que
+// mide el servidor, por la latencia de la red y los temporizadores del navegador.
+const beaconClockSlack = 2 * time.Second
+
+// RecordWatchProgress suma tiempo de reproducción a la sesión actual del espectador y guarda
+// la posición máxima alcanzada, que luego se usa para calcular el porcentaje completado.
+// El beacon no crea sesiones: la sesión la abre la petición del stream, y si no hay una abierta
+// devuelve ErrNoViewSession. Tampoco se acepta más tiempo visto que el transcurrido desde el
+// beacon anterior (ErrWatchTimeExceeded), así que enviar beacons más seguido no suma más tiempo.
+func (s *PostgresStore) RecordWatchProgress(videoID int, viewerKey string, window time.Duration, watchedSeconds, position, duration float64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// El mismo lock que RecordView, para que los beacons de una sesión se apliquen de a uno.
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, fmt.Sprintf("video_views:%d:%s", videoID, viewerKey)); err != nil {
+		return err
+	}
+	var sessionID int64
+	var elapsed float64
+	query := `SELECT id, EXTRACT(EPOCH FROM NOW() - COALESCE(progress_at, started_at))
+        FROM video_views
+        WHERE video_id = $1 AND viewer_key = $2 AND last_seen_at > NOW() - $3::float8 * INTERVAL '1 second'
+        ORDER BY last_seen_at DESC LIMIT 1`
+	err = tx.QueryRow(query, videoID, viewerKey, window.Seconds()).Scan(&sessionID, &elapsed)
+	if err == sql.ErrNoRows {
+		return ErrNoViewSession
+	}
+	if err != nil {
+		return err
+	}
+	if watchedSeconds > elapsed+beaconClockSlack.Seconds() {
+		return ErrWatchTimeExceeded
+	}
+	update := `
+    UPDATE video_views SET
+        watch_seconds = watch_seconds + $2,
+        max_position = GREATEST(max_position, $3),
+        duration = GREATEST(duration, $4),
+        last_seen_at = NOW(),
+        progress_at = NOW()
+    WHERE id = $1`
+	if _, err := tx.Exec(update, sessionID, watchedSeconds, position, duration); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RollupVideoStats recalcula los agregados por hora y por día a partir de 'since'.
+// Es idempotente: volver a ejecutarlo sobre el mismo rango sobrescribe los mismos intervalos.
+func (s *PostgresStore) RollupVideoStats(since time.Time) error {
+	for _, granularity := range []string{"hour", "day"} {
+		t := statsTables[granularity]
+		query := fmt.Sprintf(`
+        INSERT INTO %[1]s (video_id, bucket, views, unique_viewers, watch_seconds, completion_sum, completion_count)
+        SELECT video_id, date_trunc('%[2]s', started_at), COUNT(*), COUNT(DISTINCT viewer_key),
+            COALESCE(SUM(watch_seconds), 0),
+            COALESCE(SUM(LEAST(max_position / NULLIF(duration, 0), 1)), 0),
+            COUNT(*) FILTER (WHERE duration > 0)
+        FROM video_views
+        WHERE started_at >= date_trunc('%[2]s', $1::timestamptz)
+        GROUP BY 1, 2
+        ON CONFLICT (video_id, bucket) DO UPDATE SET
+            views = EXCLUDED.views,
+            unique_viewers = EXCLUDED.unique_viewers,
+            watch_seconds = EXCLUDED.watch_seconds,
+            completion_sum = EXCLUDED.completion_sum,
+            completion_count = EXCLUDED.completion_count`, t.table, t.unit)
+		if _, err := s.db.Exec(query, since); err != nil {
+			return fmt.Errorf("error al agregar las estadísticas por %s: %w", granularity, err)
+		}
+	}
+	return nil
+}
+
+// GetVideoStats arma el informe de un video en [from, to) usando los agregados de la granularidad
+// pedida. Los espectadores únicos del rango completo se cuentan sobre las vistas crudas, porque
+// los únicos por intervalo no se pueden sumar.
+func (s *PostgresStore) GetVideoStats(videoID int, from, to time.Time, granularity string) (*models.VideoStats, error) {
+	t, ok := statsTables[granularity]
+	if !ok {
+		return nil, fmt.Errorf("granularidad inválida: %s", granularity)
+	}
+	stats := &models.VideoStats{VideoID: videoID, From: from, To: to, Granularity: granularity, Buckets: []models.VideoStatsBucket{}}
+
+	query := fmt.Sprintf(`SELECT bucket, views, unique_viewers, watch_seconds, completion_sum, completion_count
+        FROM %s WHERE video_id = $1 AND bucket >= $2 AND bucket < $3 ORDER BY bucket`, t.table)
+	rows, err := s.db.Query(query, videoID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var completionSum float64
+	var completionCount int
+	for rows.Next() {
+		var b models.VideoStatsBucket
+		var sum float64
+		var count int
+		if err := rows.Scan(&b.Start, &b.Views, &b.UniqueViewers, &b.WatchSeconds, &sum, &count); err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			b.AvgCompletion = sum / float64(count)
+		}
+		stats.Views += b.Views
+		stats.TotalWatchSeconds += b.WatchSeconds
+		completionSum += sum
+		completionCount += count
+		stats.Buckets = append(stats.Buckets, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if completionCount > 0 {
+		stats.AvgCompletion = completionSum / float64(completionCount)
+	}
+
+	uniqueQuery := `SELECT COUNT(DISTINCT viewer_key) FROM video_views WHERE video_id = $1 AND started_at >= $2 AND started_at < $3`
+	if err := s.db.QueryRow(uniqueQuery, videoID, from, to).Scan(&stats.UniqueViewers); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// nullableID convierte un ID de usuario en NULL cuando es 0 (espectador anónimo).
+func nullableID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
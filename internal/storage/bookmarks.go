@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"streamvault/internal/models"
+)
+
+// createBookmarksTableSQL crea la tabla que guarda los favoritos y la lista "ver más tarde" de cada usuario.
+// Las claves foráneas con ON DELETE CASCADE hacen que, al borrar un video o un usuario,
+// sus marcadores desaparezcan automáticamente.
+const createBookmarksTableSQL = `
+    CREATE TABLE IF NOT EXISTS bookmarks (
+        user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+        list VARCHAR(20) NOT NULL CHECK (list IN ('favorites', 'watch_later')),
+        created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (user_id, video_id, list)
+    );`
+
+// AddBookmark agrega un video a una lista del usuario. Agregarlo dos veces no es un error.
+func (s *PostgresStore) AddBookmark(userID, videoID int, list string) error {
+	query := `INSERT INTO bookmarks (user_id, video_id, list) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+	_, err := s.db.Exec(query, userID, videoID, list)
+	return err
+}
+
+func (s *PostgresStore) RemoveBookmark(userID, videoID int, list string) error {
+	query := `DELETE FROM bookmarks WHERE user_id = $1 AND video_id = $2 AND list = $3`
+	_, err := s.db.Exec(query, userID, videoID, list)
+	return err
+}
+
+// GetBookmarkedVideos devuelve una página de la lista, de la más reciente a la más antigua,
+// junto con el total de elementos para que el cliente pueda paginar.
+func (s *PostgresStore) GetBookmarkedVideos(userID int, list string, limit, offset int) ([]*models.Video, int, error) {
+	var total int
+	// Los videos fuera de su ventana de publicación se ocultan, pero el marcador se conserva
+	// para cuando vuelvan a estar disponibles.
+	countQuery := `SELECT COUNT(*) FROM bookmarks b
+        JOIN videos v ON v.id = b.video_id
+        WHERE b.user_id = $1 AND b.list = $2 AND ` + videoAvailableSQL + ` AND ` + videoNotTrashedSQL
+	if err := s.db.QueryRow(countQuery, userID, list).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + videoColumns + ` FROM bookmarks b
+        JOIN videos v ON v.id = b.video_id
+        WHERE b.user_id = $1 AND b.list = $2 AND ` + videoAvailableSQL + ` AND ` + videoNotTrashedSQL + `
+        ORDER BY b.created_at DESC
+        LIMIT $3 OFFSET $4`
+	rows, err := s.db.Query(query, userID, list, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	videos := []*models.Video{}
+	for rows.Next() {
+		video, err := scanVideo(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		videos = append(videos, video)
+	}
+	return videos, total, rows.Err()
+}
@@ -0,0 +1,295 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"streamvault/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// errNoTargets se devuelve cuando una operación masiva no trae IDs ni filtro. Exigir al menos uno
+// evita que una petición mal armada afecte a toda la tabla.
+var errNoTargets = errors.New("la operación debe indicar 'ids' o al menos un criterio en 'filter'")
+
+// whereBuilder arma una cláusula WHERE con parámetros numerados ($1, $2, ...).
+// 'scope' es una condición fija que siempre se aplica pero no cuenta como criterio de selección.
+type whereBuilder struct {
+	scope string
+	conds []string
+	args  []interface{}
+}
+
+// add agrega una condición; el signo '?' se reemplaza por el parámetro correspondiente a 'arg'.
+func (b *whereBuilder) add(cond string, arg interface{}) {
+	b.args = append(b.args, arg)
+	b.conds = append(b.conds, strings.Replace(cond, "?", fmt.Sprintf("$%d", len(b.args)), 1))
+}
+
+func (b *whereBuilder) sql() string {
+	conds := b.conds
+	if b.scope != "" {
+		conds = append([]string{b.scope}, conds...)
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// resolveTargets bloquea y devuelve los IDs que coinciden con la selección, respetando el orden
+// de 'ids' cuando se envían. También devuelve los IDs pedidos que no existen.
+func resolveTargets(tx *sql.Tx, table string, ids []int, where *whereBuilder) (found, missing []int, err error) {
+	if len(ids) > 0 {
+		where.add("t.id = ANY(?)", pq.Array(ids))
+	}
+	if len(where.conds) == 0 {
+		return nil, nil, errNoTargets
+	}
+	query := fmt.Sprintf(`SELECT t.id FROM %s t WHERE %s ORDER BY t.id FOR UPDATE`, table, where.sql())
+	rows, err := tx.Query(query, where.args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	matched := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, nil, err
+		}
+		matched[id] = true
+		found = append(found, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(ids) > 0 {
+		found = found[:0]
+		for _, id := range ids {
+			if matched[id] {
+				found = append(found, id)
+				delete(matched, id)
+			} else {
+				missing = append(missing, id)
+			}
+		}
+	}
+	return found, missing, nil
+}
+
+// runBulk aplica 'apply' a cada elemento dentro de la transacción. Cada elemento usa un SAVEPOINT
+// para que un fallo no impida informar el resultado de los demás; pero si alguno falla, o si es
+// una simulación, la transacción completa se revierte.
+func runBulk(tx *sql.Tx, report *models.BulkReport, found, missing []int, apply func(id int) (string, string, error)) (bool, error) {
+	for _, id := range missing {
+		report.Results = append(report.Results, models.BulkItemResult{ID: id, Status: models.BulkStatusNotFound})
+	}
+	report.Matched = len(found)
+	failed := false
+	for _, id := range found {
+		if _, err := tx.Exec(`SAVEPOINT bulk_item`); err != nil {
+			return false, err
+		}
+		status, message, err := apply(id)
+		if err != nil {
+			if _, rbErr := tx.Exec(`ROLLBACK TO SAVEPOINT bulk_item`); rbErr != nil {
+				return false, rbErr
+			}
+			failed = true
+			status, message = models.BulkStatusError, err.Error()
+		} else if _, err := tx.Exec(`RELEASE SAVEPOINT bulk_item`); err != nil {
+			return false, err
+		}
+		report.Results = append(report.Results, models.BulkItemResult{ID: id, Status: status, Message: message})
+	}
+	if failed || report.DryRun {
+		return false, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// BulkUpdateVideos ejecuta una operación masiva sobre videos en una sola transacción.
+// El borrado mueve los videos a la papelera; los archivos se eliminan recién en la purga.
+func (s *PostgresStore) BulkUpdateVideos(req *models.BulkVideoRequest, authorID int) (*models.BulkReport, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Los videos en la papelera no son candidatos: se informan como no encontrados.
+	where := &whereBuilder{scope: "t.deleted_at IS NULL"}
+	if f := req.Filter; f != nil {
+		if f.Category != "" {
+			where.add("t.category = ?", f.Category)
+		}
+		if f.Tag != "" {
+			where.add("? = ANY(t.tags)", f.Tag)
+		}
+		if f.UploaderID != nil {
+			where.add("t.uploader_id = ?", *f.UploaderID)
+		}
+		if f.TitleContains != "" {
+			where.add(`t.title ILIKE '%' || ? || '%' ESCAPE '\'`, likeEscaper.Replace(f.TitleContains))
+		}
+	}
+	found, missing, err := resolveTargets(tx, "videos", req.IDs, where)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.BulkReport{Action: req.Action, DryRun: req.DryRun, Results: []models.BulkItemResult{}}
+	applied, err := runBulk(tx, report, found, missing, func(id int) (string, string, error) {
+		if req.Action == "delete" {
+			if _, err := tx.Exec(`UPDATE videos SET deleted_at = NOW() WHERE id = $1`, id); err != nil {
+				return "", "", err
+			}
+			return models.BulkStatusOK, "", nil
+		}
+		before, err := scanVideo(tx.QueryRow(`SELECT `+videoColumns+` FROM videos v WHERE v.id = $1`, id))
+		if err != nil {
+			return "", "", err
+		}
+		after := *before
+		switch req.Action {
+		case "set_category":
+			after.Category = req.Category
+		case "add_tags":
+			after.Tags = mergeTags(before.Tags, req.Tags)
+		case "remove_tags":
+			after.Tags = removeTags(before.Tags, req.Tags)
+		case "set_visibility":
+			if req.Visibility == "public" {
+				after.PublishAt, after.ExpireAt = nil, nil
+			} else {
+				// Un video programado se oculta cancelando su publicación: si no, la ventana
+				// quedaría invertida (expire_at antes que publish_at).
+				now := time.Now()
+				after.ExpireAt = &now
+				if after.PublishAt != nil && !after.PublishAt.Before(now) {
+					after.PublishAt = nil
+				}
+			}
+		default:
+			return "", "", fmt.Errorf("acción desconocida: %s", req.Action)
+		}
+		alreadyHidden := req.Action == "set_visibility" && req.Visibility == "hidden" &&
+			before.ExpireAt != nil && !before.ExpireAt.After(time.Now())
+		if alreadyHidden || reflect.DeepEqual(before, &after) {
+			return models.BulkStatusSkipped, "sin cambios", nil
+		}
+		// Un video que no estaba visible y pasa a público se vuelve a anunciar, igual que en UpdateVideo.
+		announce := req.Action == "set_visibility" && req.Visibility == "public" && !before.IsAvailableAt(time.Now())
+		query := `UPDATE videos SET category = $2, tags = $3, publish_at = $4, expire_at = $5,
+            publish_notified_at = CASE WHEN $6 THEN NULL ELSE publish_notified_at END, version = version + 1
+            WHERE id = $1`
+		if _, err := tx.Exec(query, id, after.Category, pq.Array(after.Tags), after.PublishAt, after.ExpireAt, announce); err != nil {
+			return "", "", err
+		}
+		// La categoría y las etiquetas forman parte del historial de metadatos; la visibilidad no.
+		if req.Action != "set_visibility" {
+			if err := saveVideoRevision(tx, before, &after, authorID); err != nil {
+				return "", "", err
+			}
+		}
+		return models.BulkStatusOK, "", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Applied = applied
+	return report, nil
+}
+
+// BulkUpdateUsers ejecuta una operación masiva sobre usuarios en una sola transacción.
+// 'actorID' es el admin que la ejecuta: su propia cuenta nunca se borra ni cambia de rol.
+func (s *PostgresStore) BulkUpdateUsers(req *models.BulkUserRequest, actorID int) (*models.BulkReport, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	where := &whereBuilder{}
+	if f := req.Filter; f != nil {
+		if f.Role != "" {
+			where.add("t.role = ?", f.Role)
+		}
+		if f.UsernameContains != "" {
+			where.add(`t.username ILIKE '%' || ? || '%' ESCAPE '\'`, likeEscaper.Replace(f.UsernameContains))
+		}
+		if f.EmailContains != "" {
+			where.add(`t.email ILIKE '%' || ? || '%' ESCAPE '\'`, likeEscaper.Replace(f.EmailContains))
+		}
+	}
+	found, missing, err := resolveTargets(tx, "users", req.IDs, where)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.BulkReport{Action: req.Action, DryRun: req.DryRun, Results: []models.BulkItemResult{}}
+	applied, err := runBulk(tx, report, found, missing, func(id int) (string, string, error) {
+		if id == actorID {
+			return models.BulkStatusSkipped, "no puedes modificar tu propia cuenta", nil
+		}
+		switch req.Action {
+		case "delete":
+			if _, err := tx.Exec(`DELETE FROM users WHERE id = $1`, id); err != nil {
+				return "", "", err
+			}
+		case "set_role":
+			res, err := tx.Exec(`UPDATE users SET role = $2 WHERE id = $1 AND role <> $2`, id, req.Role)
+			if err != nil {
+				return "", "", err
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				return models.BulkStatusSkipped, "sin cambios", nil
+			}
+		default:
+			return "", "", fmt.Errorf("acción desconocida: %s", req.Action)
+		}
+		return models.BulkStatusOK, "", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Applied = applied
+	return report, nil
+}
+
+// mergeTags agrega 'extra' a 'tags' sin duplicar y conservando el orden original.
+func mergeTags(tags, extra []string) []string {
+	result := append([]string{}, tags...)
+	for _, tag := range extra {
+		if !containsString(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// removeTags quita de 'tags' todas las etiquetas de 'remove'.
+func removeTags(tags, remove []string) []string {
+	result := []string{}
+	for _, tag := range tags {
+		if !containsString(remove, tag) {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
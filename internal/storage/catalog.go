@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"streamvault/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// ImportVideos crea o actualiza los videos del catálogo según su external_id, en una sola transacción.
+// Cada fila usa un SAVEPOINT para poder informar el resultado de todas; si alguna falla, o si es
+// una simulación, no se guarda ninguna. Los resultados se devuelven en el mismo orden que 'videos'.
+func (s *PostgresStore) ImportVideos(videos []models.CatalogVideo, dryRun bool, authorID int) (*models.ImportReport, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	report := &models.ImportReport{DryRun: dryRun, Results: []models.ImportRowResult{}}
+	failed := false
+	for i := range videos {
+		item := &videos[i]
+		if _, err := tx.Exec(`SAVEPOINT import_row`); err != nil {
+			return nil, err
+		}
+		result := models.ImportRowResult{ExternalID: item.ExternalID}
+		result.VideoID, result.Status, err = importVideo(tx, item, authorID)
+		if err != nil {
+			if _, rbErr := tx.Exec(`ROLLBACK TO SAVEPOINT import_row`); rbErr != nil {
+				return nil, rbErr
+			}
+			failed = true
+			result.Status, result.Message = models.ImportStatusError, err.Error()
+		} else if _, err := tx.Exec(`RELEASE SAVEPOINT import_row`); err != nil {
+			return nil, err
+		}
+		report.Results = append(report.Results, result)
+	}
+	if failed || dryRun {
+		return report, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	report.Applied = true
+	return report, nil
+}
+
+// importVideo aplica una fila del catálogo y devuelve el ID del video y el estado resultante.
+func importVideo(tx *sql.Tx, item *models.CatalogVideo, authorID int) (int, string, error) {
+	tags := item.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	before, err := scanVideo(tx.QueryRow(`SELECT `+videoColumns+` FROM videos v WHERE v.external_id = $1 FOR UPDATE`, item.ExternalID))
+	if err == sql.ErrNoRows {
+		if item.FilePath == "" {
+			return 0, "", fmt.Errorf("el video no existe y falta 'file_path' para crearlo")
+		}
+		var taken bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM videos WHERE file_path = $1)`, item.FilePath).Scan(&taken); err != nil {
+			return 0, "", err
+		}
+		if taken {
+			return 0, "", fmt.Errorf("el archivo '%s' ya pertenece a otro video", item.FilePath)
+		}
+		video := &models.Video{ExternalID: item.ExternalID, Title: item.Title, Description: item.Description,
+			Category: item.Category, Tags: tags, FilePath: item.FilePath, SizeBytes: item.SizeBytes,
+			PublishAt: item.PublishAt, ExpireAt: item.ExpireAt}
+		query := `INSERT INTO videos (external_id, title, description, category, tags, file_path, size_bytes, uploader_id, publish_at, expire_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
+		err := tx.QueryRow(query, video.ExternalID, video.Title, video.Description, video.Category, pq.Array(video.Tags),
+			video.FilePath, video.SizeBytes, nullableID(authorID), video.PublishAt, video.ExpireAt).Scan(&video.ID)
+		if err != nil {
+			return 0, "", err
+		}
+		if err := saveVideoRevision(tx, nil, video, authorID); err != nil {
+			return 0, "", err
+		}
+		return video.ID, models.ImportStatusCreated, nil
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	if before.DeletedAt != nil {
+		return before.ID, "", fmt.Errorf("el video está en la papelera; restáuralo antes de importarlo")
+	}
+	if item.FilePath != "" && item.FilePath != before.FilePath {
+		return before.ID, "", fmt.Errorf("'file_path' no coincide con el del video existente; la importación no cambia archivos")
+	}
+	// Los campos que el archivo no trae conservan su valor: quitar una columna de la planilla no
+	// debe borrar las etiquetas ni publicar un video programado.
+	after := *before
+	after.Title, after.Category = item.Title, item.Category
+	if item.Has("description") {
+		after.Description = item.Description
+	}
+	if item.Has("tags") {
+		after.Tags = tags
+	}
+	if item.Has("publish_at") {
+		after.PublishAt = item.PublishAt
+	}
+	if item.Has("expire_at") {
+		after.ExpireAt = item.ExpireAt
+	}
+	if after.PublishAt != nil && after.ExpireAt != nil && !after.ExpireAt.After(*after.PublishAt) {
+		return before.ID, "", fmt.Errorf("'expire_at' debe ser posterior a 'publish_at'")
+	}
+	if sameCatalogFields(before, &after) {
+		return before.ID, models.ImportStatusUnchanged, nil
+	}
+	query := `UPDATE videos SET title = $2, description = $3, category = $4, tags = $5, publish_at = $6, expire_at = $7,
+        version = version + 1
+        WHERE id = $1`
+	if _, err := tx.Exec(query, before.ID, after.Title, after.Description, after.Category, pq.Array(after.Tags),
+		after.PublishAt, after.ExpireAt); err != nil {
+		return 0, "", err
+	}
+	if err := saveVideoRevision(tx, before, &after, authorID); err != nil {
+		return 0, "", err
+	}
+	return before.ID, models.ImportStatusUpdated, nil
+}
+
+// sameCatalogFields compara los campos que la importación puede cambiar. Las fechas se comparan
+// por instante, ya que la zona horaria puede diferir entre la BD y el archivo importado.
+func sameCatalogFields(a, b *models.Video) bool {
+	return a.Title == b.Title && a.Description == b.Description && a.Category == b.Category &&
+		slices.Equal(a.Tags, b.Tags) && sameInstant(a.PublishAt, b.PublishAt) && sameInstant(a.ExpireAt, b.ExpireAt)
+}
+
+func sameInstant(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"streamvault/internal/models"
+)
+
+// createVideoChaptersTableSQL guarda los capítulos de cada video. 'source' indica si se editaron
+// a mano o se leyeron de la descripción, para que una edición manual no se pierda al cambiar la descripción.
+const createVideoChaptersTableSQL = `
+    CREATE TABLE IF NOT EXISTS video_chapters (
+        video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+        idx INTEGER NOT NULL,
+        start_ms BIGINT NOT NULL,
+        title VARCHAR(255) NOT NULL,
+        thumbnail_url TEXT NOT NULL DEFAULT '',
+        source VARCHAR(20) NOT NULL CHECK (source IN ('manual', 'description')),
+        PRIMARY KEY (video_id, idx)
+    );`
+
+// GetChapters devuelve los capítulos de un video en orden, junto con su origen
+// (cadena vacía si el video no tiene capítulos).
+func (s *PostgresStore) GetChapters(videoID int) ([]models.Chapter, string, error) {
+	query := `SELECT start_ms, title, thumbnail_url, source FROM video_chapters WHERE video_id = $1 ORDER BY idx`
+	rows, err := s.db.Query(query, videoID)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	chapters := []models.Chapter{}
+	source := ""
+	for rows.Next() {
+		var ch models.Chapter
+		var startMs int64
+		if err := rows.Scan(&startMs, &ch.Title, &ch.ThumbnailURL, &source); err != nil {
+			return nil, "", err
+		}
+		ch.Start = float64(startMs) / 1000
+		chapters = append(chapters, ch)
+	}
+	return chapters, source, rows.Err()
+}
+
+// ReplaceChapters sustituye todos los capítulos del video. Una lista vacía los elimina.
+func (s *PostgresStore) ReplaceChapters(videoID int, chapters []models.Chapter, source string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM video_chapters WHERE video_id = $1`, videoID); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO video_chapters (video_id, idx, start_ms, title, thumbnail_url, source)
+        VALUES ($1, $2, $3, $4, $5, $6)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for i, ch := range chapters {
+		if _, err := stmt.Exec(videoID, i, int64(ch.Start*1000), ch.Title, ch.ThumbnailURL, source); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
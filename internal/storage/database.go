@@ -2,10 +2,12 @@ package storage
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"streamvault/internal/models"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 /*
@@ -19,15 +21,80 @@ type DataStore interface {
 	// Métodos de Usuario
 	CreateUser(user *models.User) error
 	GetUserByEmail(email string) (*models.User, error)
+	GetUserByID(id int) (*models.User, error)
 	GetAllUsers() ([]models.User, error)
 	DeleteUser(id int) error
 	UpdateUserRole(id int, role string) error
 	// Métodos de Video
 	CreateVideo(video *models.Video) error
+	CreateVideoWithinQuota(video *models.Video, quota models.Quota) error
 	GetAllVideos() ([]*models.Video, error)
 	GetVideoByID(id int) (*models.Video, error)
-	UpdateVideo(video *models.Video) error
+	GetVideoByFilePath(filePath string) (*models.Video, error)
+	UpdateVideo(video *models.Video, authorID int) error
 	DeleteVideo(id int) error
+	ClaimDuePublications() ([]*models.Video, error)
+	GetVideosByUploader(userID int) ([]*models.Video, error)
+	SetVideoPoster(videoID int, updatedAt *time.Time) error
+	// Métodos de Importación del Catálogo
+	ImportVideos(videos []models.CatalogVideo, dryRun bool, authorID int) (*models.ImportReport, error)
+	// Métodos de Análisis de Archivos
+	SaveMediaInfo(videoID int, info *models.MediaInfo) error
+	ClaimVideoProbe(videoID int) (bool, error)
+	ClaimVideosPendingProbe(limit int, stale time.Duration) ([]*models.Video, error)
+	SetVideoMimeType(videoID int, mimeType string) error
+	// Métodos de Deduplicación
+	FindVideoByContentHash(hash string) (*models.Video, error)
+	SetVideoContentHash(videoID int, hash string) error
+	SetFileContentHash(filePath, hash string) error
+	GetVideosWithoutContentHash(afterID, limit int) ([]*models.Video, error)
+	IsFileReferenced(filePath string) (bool, error)
+	GetVideoFiles() ([]models.VideoFile, error)
+	// Métodos de Enlaces Firmados
+	ClaimUploadURL(nonce string, expiresAt time.Time) (bool, error)
+	ReleaseUploadURL(nonce string) error
+	PurgeUsedUploadURLs(before time.Time) (int64, error)
+	// Métodos de Papelera
+	TrashVideo(id int) error
+	RestoreVideo(id int) error
+	GetTrashedVideos() ([]*models.Video, error)
+	PurgeTrashedVideos(before time.Time) ([]*models.Video, error)
+	// Métodos de Historial de Revisiones
+	SaveVideoRevision(before, after *models.Video, authorID int) error
+	GetVideoRevisions(videoID int) ([]*models.VideoRevision, error)
+	GetVideoRevision(videoID, rev int) (*models.VideoRevision, error)
+	// Métodos de Subtítulos
+	SaveSubtitleTrack(track *models.SubtitleTrack, cues []models.TranscriptCue) error
+	IndexSubtitleCues(videoID int, lang string, cues []models.TranscriptCue) error
+	GetUnindexedSubtitleTracks() ([]*models.SubtitleTrack, error)
+	SearchVideos(phrase string, limit int, onlyAvailable bool) ([]*models.SearchResult, error)
+	GetSubtitleTracks(videoID int) ([]models.SubtitleTrack, error)
+	GetSubtitleTrack(videoID int, lang string) (*models.SubtitleTrack, error)
+	DeleteSubtitleTrack(videoID int, lang string) (bool, error)
+	// Métodos de Capítulos
+	GetChapters(videoID int) ([]models.Chapter, string, error)
+	ReplaceChapters(videoID int, chapters []models.Chapter, source string) error
+	// Métodos de Operaciones Masivas
+	BulkUpdateVideos(req *models.BulkVideoRequest, authorID int) (*models.BulkReport, error)
+	BulkUpdateUsers(req *models.BulkUserRequest, actorID int) (*models.BulkReport, error)
+	// Métodos de Cuotas
+	GetUserQuota(userID int) (*models.Quota, error)
+	SetUserQuota(userID int, quota models.Quota) error
+	GetUploadUsage(userID int) (bytes int64, videos int, err error)
+	// Métodos de Marcadores (favoritos y "ver más tarde")
+	AddBookmark(userID, videoID int, list string) error
+	RemoveBookmark(userID, videoID int, list string) error
+	GetBookmarkedVideos(userID int, list string, limit, offset int) ([]*models.Video, int, error)
+	// Métodos de Analíticas
+	RecordView(videoID, userID int, viewerKey string, window time.Duration) error
+	RecordWatchProgress(videoID int, viewerKey string, window time.Duration, watchedSeconds, position, duration float64) error
+	RollupVideoStats(since time.Time) error
+	GetVideoStats(videoID int, from, to time.Time, granularity string) (*models.VideoStats, error)
+	// Métodos de Recomendaciones
+	GetCoWatchCounts() ([]models.CoWatch, error)
+	ReplaceRelatedVideos(related map[int][]models.RelatedScore) error
+	GetRelatedVideos(videoID, limit int) ([]*models.Video, error)
+	GetVideosInCategory(category string, excludeID, limit int, onlyAvailable bool) ([]*models.Video, error)
 }
 
 // PostgresStore es la IMPLEMENTACIÓN CONCRETA de la interfaz DataStore.
@@ -70,18 +137,160 @@ func (s *PostgresStore) Init() error {
         uploaded_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
     );`
 
-	// Ejecutamos ambas consultas para crear las tablas.
-	if _, err := s.db.Exec(createUsersTableSQL); err != nil {
-		return fmt.Errorf("error al crear la tabla users: %w", err)
-	}
+	// Columnas agregadas después de la primera versión del esquema.
+	addVideoTagsSQL := `ALTER TABLE videos ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';`
+	// publish_notified_at marca los videos cuya publicación ya se anunció. Los videos que
+	// existían antes de esta columna se dan por anunciados (el DEFAULT solo aplica al crearla).
+	addVideoScheduleSQL := `
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP WITH TIME ZONE;
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS expire_at TIMESTAMP WITH TIME ZONE;
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS publish_notified_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+    ALTER TABLE videos ALTER COLUMN publish_notified_at DROP DEFAULT;`
+
+	addVideoVersionSQL := `ALTER TABLE videos ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;`
+
+	addVideoOwnershipSQL := `
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS uploader_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS size_bytes BIGINT NOT NULL DEFAULT 0;
+    CREATE INDEX IF NOT EXISTS videos_uploader_idx ON videos (uploader_id);`
+
+	// poster_updated_at es NULL mientras el video no tenga portada.
+	addVideoPosterSQL := `ALTER TABLE videos ADD COLUMN IF NOT EXISTS poster_updated_at TIMESTAMP WITH TIME ZONE;`
+
+	// external_id es un identificador estable para importar y exportar el catálogo entre instalaciones.
+	// El DEFAULT se evalúa por fila, así que los videos existentes también reciben uno propio.
+	addVideoExternalIDSQL := `
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS external_id VARCHAR(100) NOT NULL DEFAULT gen_random_uuid()::text;
+    CREATE UNIQUE INDEX IF NOT EXISTS videos_external_id_idx ON videos (external_id);`
+
+	// content_sha256 no es único: un admin puede asociar varios videos al mismo archivo.
+	addVideoContentHashSQL := `
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS content_sha256 CHAR(64);
+    CREATE INDEX IF NOT EXISTS videos_content_sha256_idx ON videos (content_sha256);`
+
+	// content_sha256 es el hash del archivo tal como se subió, que es con el que se comparan las
+	// subidas siguientes. file_sha256 es el del archivo guardado cuando se reescribió después
+	// (por ejemplo, al mover 'moov' al principio); NULL si no cambió.
+	addVideoFileHashSQL := `
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS file_sha256 CHAR(64);
+    CREATE INDEX IF NOT EXISTS videos_file_sha256_idx ON videos (file_sha256);`
+
+	// mime_type es el tipo detectado por los primeros bytes del archivo, no el que declara el cliente.
+	addVideoMimeTypeSQL := `
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS mime_type VARCHAR(100) NOT NULL DEFAULT '';`
+
+	// source_path es la ruta absoluta del archivo de los videos importados de una biblioteca sin
+	// copiarlo (NULL para los archivos de UPLOAD_DIR). release_year es el año de estreno que trae el NFO.
+	addVideoLibrarySQL := `
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS source_path TEXT;
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS release_year INTEGER;`
+
+	// Columnas media_*: resultado del análisis del archivo (ver models.MediaInfo).
+	addVideoMediaSQL := `
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS media_status VARCHAR(20) NOT NULL DEFAULT 'pending';
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS media_error TEXT NOT NULL DEFAULT '';
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS media_container VARCHAR(20) NOT NULL DEFAULT '';
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS media_duration DOUBLE PRECISION NOT NULL DEFAULT 0;
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS media_width INTEGER NOT NULL DEFAULT 0;
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS media_height INTEGER NOT NULL DEFAULT 0;
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS media_video_codec VARCHAR(50) NOT NULL DEFAULT '';
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS media_audio_codec VARCHAR(50) NOT NULL DEFAULT '';
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS media_bitrate BIGINT NOT NULL DEFAULT 0;
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS media_created_at TIMESTAMP WITH TIME ZONE;
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS media_probing_at TIMESTAMP WITH TIME ZONE;`
+
+	// deleted_at marca los videos que están en la papelera.
+	addVideoTrashSQL := `
+    ALTER TABLE videos ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+    CREATE INDEX IF NOT EXISTS videos_deleted_at_idx ON videos (deleted_at) WHERE deleted_at IS NOT NULL;`
 
-	if _, err := s.db.Exec(createVideosTableSQL); err != nil {
-		return fmt.Errorf("error al crear la tabla videos: %w", err)
+	// El orden importa: las tablas con claves foráneas deben crearse después de las tablas a las que apuntan.
+	statements := []struct{ name, sql string }{
+		{"la tabla users", createUsersTableSQL},
+		{"la tabla videos", createVideosTableSQL},
+		{"la columna videos.tags", addVideoTagsSQL},
+		{"las columnas de publicación programada", addVideoScheduleSQL},
+		{"las columnas de autoría de videos", addVideoOwnershipSQL},
+		{"la columna videos.version", addVideoVersionSQL},
+		{"la columna videos.poster_updated_at", addVideoPosterSQL},
+		{"la columna videos.deleted_at", addVideoTrashSQL},
+		{"la columna videos.external_id", addVideoExternalIDSQL},
+		{"la columna videos.content_sha256", addVideoContentHashSQL},
+		{"la columna videos.file_sha256", addVideoFileHashSQL},
+		{"las columnas de análisis del archivo", addVideoMediaSQL},
+		{"la columna videos.mime_type", addVideoMimeTypeSQL},
+		{"las columnas de la biblioteca importada", addVideoLibrarySQL},
+		{"la tabla user_quotas", createUserQuotasTableSQL},
+		{"la tabla video_revisions", createVideoRevisionsTableSQL},
+		{"la tabla subtitle_tracks", createSubtitleTracksTableSQL},
+		{"la tabla subtitle_cues", createSubtitleCuesTableSQL},
+		{"la tabla video_chapters", createVideoChaptersTableSQL},
+		{"la tabla bookmarks", createBookmarksTableSQL},
+		{"la tabla video_views", createVideoViewsTableSQL},
+		{"las tablas de estadísticas", createVideoStatsTablesSQL},
+		{"la tabla related_videos", createRelatedVideosTableSQL},
+		{"la tabla used_upload_urls", createUsedUploadURLsTableSQL},
+	}
+	for _, st := range statements {
+		if _, err := s.db.Exec(st.sql); err != nil {
+			return fmt.Errorf("error al crear %s: %w", st.name, err)
+		}
 	}
 
 	return nil
 }
 
+// videoColumns es la lista de columnas que se leen para construir un models.Video.
+// Las consultas que la usan deben referirse a la tabla de videos con el alias 'v'.
+const videoColumns = `v.id, v.title, v.description, v.category, v.tags, v.file_path, v.size_bytes, v.uploaded_at,
+    v.uploader_id, v.version, v.external_id, v.content_sha256, v.publish_at, v.expire_at, v.poster_updated_at, v.deleted_at,
+    v.media_status, v.media_error, v.media_container, v.media_duration, v.media_width, v.media_height,
+    v.media_video_codec, v.media_audio_codec, v.media_bitrate, v.media_created_at, v.mime_type,
+    v.source_path, v.release_year`
+
+// videoNotTrashedSQL es la condición que cumplen los videos que no están en la papelera.
+// Todas las consultas de videos deben incluirla, salvo las de la propia papelera.
+const videoNotTrashedSQL = `v.deleted_at IS NULL`
+
+// videoAvailableSQL es la condición que cumplen los videos dentro de su ventana de publicación.
+const videoAvailableSQL = `(v.publish_at IS NULL OR v.publish_at <= NOW()) AND (v.expire_at IS NULL OR v.expire_at > NOW())`
+
+// rowScanner abstrae *sql.Row y *sql.Rows para poder reutilizar scanVideo con ambos.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanVideo lee una fila con las columnas de videoColumns.
+func scanVideo(row rowScanner) (*models.Video, error) {
+	video := new(models.Video)
+	var uploaderID sql.NullInt64
+	var posterUpdatedAt sql.NullTime
+	var contentHash, sourcePath sql.NullString
+	var releaseYear sql.NullInt64
+	err := row.Scan(&video.ID, &video.Title, &video.Description, &video.Category, pq.Array(&video.Tags), &video.FilePath,
+		&video.SizeBytes, &video.UploadedAt, &uploaderID, &video.Version, &video.ExternalID, &contentHash, &video.PublishAt, &video.ExpireAt, &posterUpdatedAt, &video.DeletedAt,
+		&video.Media.Status, &video.Media.Error, &video.Media.Container, &video.Media.DurationSeconds, &video.Media.Width,
+		&video.Media.Height, &video.Media.VideoCodec, &video.Media.AudioCodec, &video.Media.Bitrate, &video.Media.CreatedAt, &video.MimeType,
+		&sourcePath, &releaseYear)
+	if err != nil {
+		return nil, err
+	}
+	if uploaderID.Valid {
+		id := int(uploaderID.Int64)
+		video.UploaderID = &id
+	}
+	video.ContentHash = contentHash.String
+	video.SourcePath = sourcePath.String
+	if releaseYear.Valid {
+		year := int(releaseYear.Int64)
+		video.ReleaseYear = &year
+	}
+	if posterUpdatedAt.Valid {
+		video.Poster = models.NewPoster(video.ID, posterUpdatedAt.Time)
+	}
+	return video, nil
+}
+
 // CreateUser se ha simplificado para coincidir con la nueva estructura de la BD.
 func (s *PostgresStore) CreateUser(user *models.User) error {
 	query := `INSERT INTO users (username, email, password_hash, role) VALUES ($1, $2, $3, $4) RETURNING id, created_at`
@@ -102,6 +311,20 @@ func (s *PostgresStore) GetUserByEmail(email string) (*models.User, error) {
 	return user, nil
 }
 
+// GetUserByID devuelve el usuario sin su contraseña.
+func (s *PostgresStore) GetUserByID(id int) (*models.User, error) {
+	user := new(models.User)
+	query := `SELECT id, username, email, role, created_at FROM users WHERE id = $1`
+	err := s.db.QueryRow(query, id).Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("usuario no encontrado")
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
 func (s *PostgresStore) GetAllUsers() ([]models.User, error) {
 	query := `SELECT id, username, email, role, created_at FROM users`
 	rows, err := s.db.Query(query)
@@ -133,12 +356,29 @@ func (s *PostgresStore) UpdateUserRole(id int, role string) error {
 }
 
 func (s *PostgresStore) CreateVideo(video *models.Video) error {
-	query := `INSERT INTO videos (title, description, category, file_path) VALUES ($1, $2, $3, $4) RETURNING id, uploaded_at`
-	return s.db.QueryRow(query, video.Title, video.Description, video.Category, video.FilePath).Scan(&video.ID, &video.UploadedAt)
+	return insertVideo(s.db, video)
+}
+
+// queryRower abstrae *sql.DB y *sql.Tx para poder crear un video dentro o fuera de una transacción.
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// insertVideo inserta el video y completa su ID, fecha de subida e identificador externo.
+func insertVideo(q queryRower, video *models.Video) error {
+	if video.Tags == nil {
+		video.Tags = []string{}
+	}
+	query := `INSERT INTO videos (title, description, category, tags, file_path, size_bytes, uploader_id, publish_at, expire_at,
+            content_sha256, mime_type, source_path, release_year, media_duration)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, NULLIF($12, ''), $13, $14) RETURNING id, uploaded_at, external_id`
+	return q.QueryRow(query, video.Title, video.Description, video.Category, pq.Array(video.Tags), video.FilePath,
+		video.SizeBytes, video.UploaderID, video.PublishAt, video.ExpireAt, video.ContentHash, video.MimeType,
+		video.SourcePath, video.ReleaseYear, video.Media.DurationSeconds).Scan(&video.ID, &video.UploadedAt, &video.ExternalID)
 }
 
 func (s *PostgresStore) GetAllVideos() ([]*models.Video, error) {
-	query := `SELECT id, title, description, category, file_path, uploaded_at FROM videos`
+	query := `SELECT ` + videoColumns + ` FROM videos v WHERE ` + videoNotTrashedSQL
 	rows, err := s.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -146,8 +386,8 @@ func (s *PostgresStore) GetAllVideos() ([]*models.Video, error) {
 	defer rows.Close()
 	var videos []*models.Video
 	for rows.Next() {
-		video := new(models.Video)
-		if err := rows.Scan(&video.ID, &video.Title, &video.Description, &video.Category, &video.FilePath, &video.UploadedAt); err != nil {
+		video, err := scanVideo(rows)
+		if err != nil {
 			return nil, err
 		}
 		videos = append(videos, video)
@@ -156,9 +396,8 @@ func (s *PostgresStore) GetAllVideos() ([]*models.Video, error) {
 }
 
 func (s *PostgresStore) GetVideoByID(id int) (*models.Video, error) {
-	video := new(models.Video)
-	query := `SELECT id, title, description, category, file_path, uploaded_at FROM videos WHERE id = $1`
-	err := s.db.QueryRow(query, id).Scan(&video.ID, &video.Title, &video.Description, &video.Category, &video.FilePath, &video.UploadedAt)
+	query := `SELECT ` + videoColumns + ` FROM videos v WHERE v.id = $1 AND ` + videoNotTrashedSQL
+	video, err := scanVideo(s.db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("video no encontrado")
@@ -168,10 +407,96 @@ func (s *PostgresStore) GetVideoByID(id int) (*models.Video, error) {
 	return video, nil
 }
 
-func (s *PostgresStore) UpdateVideo(video *models.Video) error {
-	query := `UPDATE videos SET title = $1, description = $2, category = $3 WHERE id = $4`
-	_, err := s.db.Exec(query, video.Title, video.Description, video.Category, video.ID)
-	return err
+func (s *PostgresStore) GetVideoByFilePath(filePath string) (*models.Video, error) {
+	// Varios videos pueden compartir un archivo; se prefiere uno que esté publicado.
+	query := `SELECT ` + videoColumns + ` FROM videos v WHERE v.file_path = $1 AND ` + videoNotTrashedSQL + `
+        ORDER BY (` + videoAvailableSQL + `) DESC, v.id
+        LIMIT 1`
+	video, err := scanVideo(s.db.QueryRow(query, filePath))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("video no encontrado")
+		}
+		return nil, err
+	}
+	return video, nil
+}
+
+// ErrVersionConflict indica que el video cambió desde la versión que el cliente tenía.
+var ErrVersionConflict = errors.New("el video fue modificado por otra persona")
+
+// ErrVideoNotFound indica que el video que se quería modificar no existe o está en la papelera.
+var ErrVideoNotFound = errors.New("video no encontrado")
+
+// UpdateVideo guarda los metadatos editables y aumenta la versión del video. Si video.Version no es 0,
+// la actualización solo se aplica si coincide con la versión guardada; si no, devuelve ErrVersionConflict.
+// Si el video no existe o está en la papelera, devuelve ErrVideoNotFound.
+// En la misma transacción se guarda la revisión del historial a nombre de 'authorID', así que la
+// edición y su revisión se aplican juntas o no se aplica ninguna. Al terminar, video.Version
+// contiene la versión nueva.
+func (s *PostgresStore) UpdateVideo(video *models.Video, authorID int) error {
+	if video.Tags == nil {
+		video.Tags = []string{}
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// El bloqueo de la fila ordena las ediciones simultáneas, también al numerar sus revisiones.
+	before, err := scanVideo(tx.QueryRow(`SELECT `+videoColumns+` FROM videos v WHERE v.id = $1 AND `+videoNotTrashedSQL+` FOR UPDATE`, video.ID))
+	if err == sql.ErrNoRows {
+		return ErrVideoNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if video.Version != 0 && video.Version != before.Version {
+		return ErrVersionConflict
+	}
+	// Si se mueve publish_at, la publicación se vuelve a anunciar cuando llegue la nueva fecha.
+	query := `UPDATE videos v SET title = $1, description = $2, category = $3, tags = $4, publish_at = $5, expire_at = $6,
+        publish_notified_at = CASE WHEN v.publish_at IS DISTINCT FROM $5 AND $5 > NOW() THEN NULL ELSE v.publish_notified_at END,
+        release_year = $9, version = v.version + 1
+        WHERE v.id = $7 AND ($8::integer = 0 OR v.version = $8) AND ` + videoNotTrashedSQL + `
+        RETURNING v.version`
+	err = tx.QueryRow(query, video.Title, video.Description, video.Category, pq.Array(video.Tags),
+		video.PublishAt, video.ExpireAt, video.ID, video.Version, video.ReleaseYear).Scan(&video.Version)
+	if err == sql.ErrNoRows {
+		return ErrVersionConflict
+	}
+	if err != nil {
+		return err
+	}
+	if err := saveVideoRevision(tx, before, video, authorID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ClaimDuePublications marca como anunciados, y devuelve, los videos cuya fecha de publicación
+// ya llegó y que todavía no se anunciaron. Al marcar y leer en la misma sentencia, cada video
+// se anuncia una sola vez aunque la tarea se ejecute en paralelo.
+func (s *PostgresStore) ClaimDuePublications() ([]*models.Video, error) {
+	query := `UPDATE videos v SET publish_notified_at = NOW()
+        WHERE v.publish_notified_at IS NULL AND COALESCE(v.publish_at, v.uploaded_at) <= NOW()
+            AND (v.expire_at IS NULL OR v.expire_at > NOW()) AND ` + videoNotTrashedSQL + `
+        RETURNING ` + videoColumns
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var videos []*models.Video
+	for rows.Next() {
+		video, err := scanVideo(rows)
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+	return videos, rows.Err()
 }
 
 func (s *PostgresStore) DeleteVideo(id int) error {
@@ -179,3 +504,16 @@ func (s *PostgresStore) DeleteVideo(id int) error {
 	_, err := s.db.Exec(query, id)
 	return err
 }
+
+// SetVideoPoster registra cuándo se actualizó la portada del video (nil si se quitó).
+// No aumenta la versión: la portada no forma parte de los metadatos editables.
+func (s *PostgresStore) SetVideoPoster(videoID int, updatedAt *time.Time) error {
+	res, err := s.db.Exec(`UPDATE videos SET poster_updated_at = $2 WHERE id = $1`, videoID, updatedAt)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("video no encontrado")
+	}
+	return nil
+}
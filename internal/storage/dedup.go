@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"database/sql"
+
+	"streamvault/internal/models"
+)
+
+// FindVideoByContentHash devuelve el video más antiguo (fuera de la papelera) con ese contenido,
+// o nil si no hay ninguno. Se compara tanto con el archivo subido como con el archivo guardado,
+// por si se reescribió: así se detecta la misma subida y también una copia del archivo optimizado.
+func (s *PostgresStore) FindVideoByContentHash(hash string) (*models.Video, error) {
+	query := `SELECT ` + videoColumns + ` FROM videos v
+        WHERE (v.content_sha256 = $1 OR v.file_sha256 = $1) AND ` + videoNotTrashedSQL + `
+        ORDER BY v.id
+        LIMIT 1`
+	video, err := scanVideo(s.db.QueryRow(query, hash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return video, err
+}
+
+// SetVideoContentHash guarda el hash calculado para un video existente.
+func (s *PostgresStore) SetVideoContentHash(videoID int, hash string) error {
+	_, err := s.db.Exec(`UPDATE videos SET content_sha256 = $2 WHERE id = $1`, videoID, hash)
+	return err
+}
+
+// SetFileContentHash guarda el hash del archivo reescrito en todos los videos que lo usan. El
+// hash de la subida (content_sha256) no cambia.
+func (s *PostgresStore) SetFileContentHash(filePath, hash string) error {
+	_, err := s.db.Exec(`UPDATE videos SET file_sha256 = $2 WHERE file_path = $1`, filePath, hash)
+	return err
+}
+
+// GetVideosWithoutContentHash devuelve videos cuyo hash todavía no se calculó (los subidos antes
+// de la deduplicación o creados por la importación del catálogo), en orden de ID a partir del
+// siguiente a 'afterID'.
+func (s *PostgresStore) GetVideosWithoutContentHash(afterID, limit int) ([]*models.Video, error) {
+	query := `SELECT ` + videoColumns + ` FROM videos v WHERE v.content_sha256 IS NULL AND v.id > $1 ORDER BY v.id LIMIT $2`
+	rows, err := s.db.Query(query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var videos []*models.Video
+	for rows.Next() {
+		video, err := scanVideo(rows)
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+	return videos, rows.Err()
+}
+
+// IsFileReferenced indica si algún video, incluidos los de la papelera, usa el archivo.
+// Se consulta antes de borrar un archivo que podría estar compartido.
+func (s *PostgresStore) IsFileReferenced(filePath string) (bool, error) {
+	var referenced bool
+	err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM videos WHERE file_path = $1)`, filePath).Scan(&referenced)
+	return referenced, err
+}
+
+// GetVideoFiles devuelve el archivo de cada video, incluidos los de la papelera.
+func (s *PostgresStore) GetVideoFiles() ([]models.VideoFile, error) {
+	rows, err := s.db.Query(`SELECT id, title, file_path, COALESCE(source_path, ''), deleted_at IS NOT NULL FROM videos ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	files := []models.VideoFile{}
+	for rows.Next() {
+		var f models.VideoFile
+		if err := rows.Scan(&f.VideoID, &f.Title, &f.FilePath, &f.SourcePath, &f.Trashed); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"time"
+
+	"streamvault/internal/models"
+)
+
+// SaveMediaInfo guarda el resultado del análisis del archivo de un video.
+func (s *PostgresStore) SaveMediaInfo(videoID int, info *models.MediaInfo) error {
+	query := `UPDATE videos SET media_status = $2, media_error = $3, media_container = $4, media_duration = $5,
+        media_width = $6, media_height = $7, media_video_codec = $8, media_audio_codec = $9, media_bitrate = $10,
+        media_created_at = $11
+        WHERE id = $1`
+	_, err := s.db.Exec(query, videoID, info.Status, info.Error, info.Container, info.DurationSeconds, info.Width,
+		info.Height, info.VideoCodec, info.AudioCodec, info.Bitrate, info.CreatedAt)
+	return err
+}
+
+// SetVideoMimeType guarda el tipo MIME detectado en el archivo de un video.
+func (s *PostgresStore) SetVideoMimeType(videoID int, mimeType string) error {
+	_, err := s.db.Exec(`UPDATE videos SET mime_type = $2 WHERE id = $1`, videoID, mimeType)
+	return err
+}
+
+// ClaimVideoProbe marca el video como "probing" si todavía está pendiente, para que ninguna otra
+// tarea lo analice a la vez. Devuelve false si ya lo tomó otra.
+func (s *PostgresStore) ClaimVideoProbe(videoID int) (bool, error) {
+	res, err := s.db.Exec(`UPDATE videos SET media_status = 'probing', media_probing_at = NOW()
+        WHERE id = $1 AND media_status = 'pending'`, videoID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ClaimVideosPendingProbe marca como "probing", y devuelve, hasta 'limit' videos cuyo archivo
+// todavía no se analizó, los más antiguos primero. También recupera los que quedaron en "probing"
+// hace más de 'stale' (por ejemplo, porque el proceso se reinició a mitad del análisis).
+func (s *PostgresStore) ClaimVideosPendingProbe(limit int, stale time.Duration) ([]*models.Video, error) {
+	query := `UPDATE videos v SET media_status = 'probing', media_probing_at = NOW()
+        WHERE v.id IN (
+            SELECT id FROM videos
+            WHERE media_status = 'pending'
+                OR (media_status = 'probing' AND media_probing_at < NOW() - $2::float8 * INTERVAL '1 second')
+            ORDER BY id LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING ` + videoColumns
+	rows, err := s.db.Query(query, limit, stale.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var videos []*models.Video
+	for rows.Next() {
+		video, err := scanVideo(rows)
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+	return videos, rows.Err()
+}
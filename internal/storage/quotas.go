@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+
+	"streamvault/internal/models"
+)
+
+// createUserQuotasTableSQL guarda las cuotas asignadas a usuarios concretos. Una columna NULL
+// indica que ese límite se hereda de la cuota por defecto del rol del usuario.
+const createUserQuotasTableSQL = `
+    CREATE TABLE IF NOT EXISTS user_quotas (
+        user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+        max_bytes BIGINT,
+        max_videos INTEGER
+    );`
+
+// GetUserQuota devuelve la cuota propia del usuario. Si no tiene una, devuelve una cuota vacía.
+func (s *PostgresStore) GetUserQuota(userID int) (*models.Quota, error) {
+	var maxBytes, maxVideos sql.NullInt64
+	query := `SELECT max_bytes, max_videos FROM user_quotas WHERE user_id = $1`
+	err := s.db.QueryRow(query, userID).Scan(&maxBytes, &maxVideos)
+	if err == sql.ErrNoRows {
+		return &models.Quota{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	quota := &models.Quota{}
+	if maxBytes.Valid {
+		quota.MaxBytes = &maxBytes.Int64
+	}
+	if maxVideos.Valid {
+		n := int(maxVideos.Int64)
+		quota.MaxVideos = &n
+	}
+	return quota, nil
+}
+
+func (s *PostgresStore) SetUserQuota(userID int, quota models.Quota) error {
+	query := `INSERT INTO user_quotas (user_id, max_bytes, max_videos) VALUES ($1, $2, $3)
+        ON CONFLICT (user_id) DO UPDATE SET max_bytes = EXCLUDED.max_bytes, max_videos = EXCLUDED.max_videos`
+	_, err := s.db.Exec(query, userID, quota.MaxBytes, quota.MaxVideos)
+	return err
+}
+
+// uploadUsageSQL suma el espacio y la cantidad de videos de un usuario. Los videos de la papelera
+// cuentan hasta que se purgan, porque su archivo sigue ocupando el disco: si no, se podría subir,
+// borrar y volver a subir para superar la cuota. Cada video cuenta su tamaño aunque comparta el
+// archivo con otro: asociar un duplicado solo lo pueden hacer los admins, y la cuota mide lo que
+// el usuario tiene en el catálogo, no el espacio que se ahorra al compartir.
+const uploadUsageSQL = `SELECT COALESCE(SUM(size_bytes), 0), COUNT(*) FROM videos WHERE uploader_id = $1`
+
+// ErrQuotaExceeded indica que el video no entra en la cuota de quien lo sube.
+var ErrQuotaExceeded = errors.New("cuota de subida excedida")
+
+// CreateVideoWithinQuota crea el video solo si, contándolo, quien lo sube no supera 'quota'; si
+// no entra devuelve ErrQuotaExceeded. La fila del usuario se bloquea durante la transacción para
+// que dos subidas simultáneas no pasen la comprobación a la vez.
+func (s *PostgresStore) CreateVideoWithinQuota(video *models.Video, quota models.Quota) error {
+	if video.UploaderID == nil {
+		return s.CreateVideo(video)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`SELECT id FROM users WHERE id = $1 FOR UPDATE`, *video.UploaderID); err != nil {
+		return err
+	}
+	var bytes int64
+	var videos int
+	if err := tx.QueryRow(uploadUsageSQL, *video.UploaderID).Scan(&bytes, &videos); err != nil {
+		return err
+	}
+	if (quota.MaxVideos != nil && videos+1 > *quota.MaxVideos) || (quota.MaxBytes != nil && bytes+video.SizeBytes > *quota.MaxBytes) {
+		return ErrQuotaExceeded
+	}
+	if err := insertVideo(tx, video); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetUploadUsage suma el espacio y la cantidad de videos subidos por el usuario, incluidos los
+// de la papelera que todavía no se purgaron.
+func (s *PostgresStore) GetUploadUsage(userID int) (int64, int, error) {
+	var bytes int64
+	var videos int
+	err := s.db.QueryRow(uploadUsageSQL, userID).Scan(&bytes, &videos)
+	return bytes, videos, err
+}
+
+func (s *PostgresStore) GetVideosByUploader(userID int) ([]*models.Video, error) {
+	query := `SELECT ` + videoColumns + ` FROM videos v WHERE v.uploader_id = $1 AND ` + videoNotTrashedSQL + ` ORDER BY v.uploaded_at DESC`
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	videos := []*models.Video{}
+	for rows.Next() {
+		video, err := scanVideo(rows)
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+	return videos, rows.Err()
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"streamvault/internal/models"
+)
+
+// createRelatedVideosTableSQL guarda las recomendaciones precalculadas por la tarea en segundo plano.
+const createRelatedVideosTableSQL = `
+    CREATE TABLE IF NOT EXISTS related_videos (
+        video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+        related_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+        score DOUBLE PRECISION NOT NULL,
+        computed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (video_id, related_id)
+    );`
+
+// GetCoWatchCounts cuenta, para cada par de videos, los espectadores que vieron ambos
+// en los últimos 90 días. Cada par se devuelve una sola vez, con VideoA < VideoB.
+func (s *PostgresStore) GetCoWatchCounts() ([]models.CoWatch, error) {
+	query := `
+    WITH viewers AS (
+        SELECT DISTINCT video_id, viewer_key FROM video_views
+        WHERE started_at > NOW() - INTERVAL '90 days'
+    )
+    SELECT a.video_id, b.video_id, COUNT(*)
+    FROM viewers a JOIN viewers b ON a.viewer_key = b.viewer_key AND a.video_id < b.video_id
+    GROUP BY 1, 2`
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var pairs []models.CoWatch
+	for rows.Next() {
+		var p models.CoWatch
+		if err := rows.Scan(&p.VideoA, &p.VideoB, &p.Viewers); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, rows.Err()
+}
+
+// ReplaceRelatedVideos sustituye todas las recomendaciones precalculadas en una sola transacción,
+// para que los lectores nunca vean la tabla a medio escribir.
+func (s *PostgresStore) ReplaceRelatedVideos(related map[int][]models.RelatedScore) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM related_videos`); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO related_videos (video_id, related_id, score) VALUES ($1, $2, $3)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for videoID, scores := range related {
+		for _, rs := range scores {
+			if _, err := stmt.Exec(videoID, rs.VideoID, rs.Score); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// GetRelatedVideos devuelve las recomendaciones precalculadas de un video, de mayor a menor puntuación.
+func (s *PostgresStore) GetRelatedVideos(videoID, limit int) ([]*models.Video, error) {
+	query := `SELECT ` + videoColumns + ` FROM related_videos r
+        JOIN videos v ON v.id = r.related_id
+        WHERE r.video_id = $1 AND ` + videoNotTrashedSQL + `
+        ORDER BY r.score DESC
+        LIMIT $2`
+	rows, err := s.db.Query(query, videoID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	videos := []*models.Video{}
+	for rows.Next() {
+		video, err := scanVideo(rows)
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+	return videos, rows.Err()
+}
+
+// GetVideosInCategory devuelve los videos más recientes de la categoría, sin 'excludeID'. Con
+// onlyAvailable solo incluye los que están dentro de su ventana de publicación.
+func (s *PostgresStore) GetVideosInCategory(category string, excludeID, limit int, onlyAvailable bool) ([]*models.Video, error) {
+	visible := videoNotTrashedSQL
+	if onlyAvailable {
+		visible += ` AND ` + videoAvailableSQL
+	}
+	query := `SELECT ` + videoColumns + ` FROM videos v
+        WHERE LOWER(v.category) = LOWER($1) AND v.id <> $2 AND ` + visible + `
+        ORDER BY v.uploaded_at DESC
+        LIMIT $3`
+	rows, err := s.db.Query(query, category, excludeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	videos := []*models.Video{}
+	for rows.Next() {
+		video, err := scanVideo(rows)
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+	return videos, rows.Err()
+}
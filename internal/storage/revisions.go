@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"streamvault/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// createVideoRevisionsTableSQL guarda el historial de metadatos de cada video. El autor pasa a NULL
+// si se elimina su cuenta, pero la revisión se conserva.
+const createVideoRevisionsTableSQL = `
+    CREATE TABLE IF NOT EXISTS video_revisions (
+        video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+        rev INTEGER NOT NULL,
+        title VARCHAR(255) NOT NULL,
+        description TEXT,
+        category VARCHAR(100) NOT NULL,
+        tags TEXT[] NOT NULL DEFAULT '{}',
+        author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+        created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (video_id, rev)
+    );`
+
+// SaveVideoRevision guarda los metadatos de 'after' como una revisión nueva. Si el video es
+// anterior al historial y todavía no tiene revisiones, 'before' (si no es nil) se guarda primero
+// como revisión base sin autor, para que el primer cambio también pueda deshacerse.
+func (s *PostgresStore) SaveVideoRevision(before, after *models.Video, authorID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := saveVideoRevision(tx, before, after, authorID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// saveVideoRevision es la implementación de SaveVideoRevision sobre una transacción ya abierta,
+// para que otras operaciones puedan guardar revisiones como parte de su propia transacción.
+// Si ningún campo del historial cambió respecto de 'before', no se guarda nada.
+func saveVideoRevision(tx *sql.Tx, before, after *models.Video, authorID int) error {
+	if before != nil && sameRevisionFields(before, after) {
+		return nil
+	}
+	if before != nil {
+		var count int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM video_revisions WHERE video_id = $1`, after.ID).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			if err := insertVideoRevision(tx, before, 0); err != nil {
+				return err
+			}
+		}
+	}
+	return insertVideoRevision(tx, after, authorID)
+}
+
+// sameRevisionFields compara los campos que se guardan en el historial.
+func sameRevisionFields(a, b *models.Video) bool {
+	return a.Title == b.Title && a.Description == b.Description && a.Category == b.Category &&
+		slices.Equal(a.Tags, b.Tags)
+}
+
+// insertVideoRevision agrega la revisión con el número siguiente a la última guardada.
+func insertVideoRevision(tx *sql.Tx, video *models.Video, authorID int) error {
+	tags := video.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	query := `INSERT INTO video_revisions (video_id, rev, title, description, category, tags, author_id)
+        SELECT $1::integer, COALESCE(MAX(rev), 0) + 1, $2::varchar, $3::text, $4::varchar, $5::text[], $6::integer
+        FROM video_revisions WHERE video_id = $1`
+	_, err := tx.Exec(query, video.ID, video.Title, video.Description, video.Category, pq.Array(tags), nullableID(authorID))
+	return err
+}
+
+// GetVideoRevisions devuelve el historial de un video, de la revisión más antigua a la más reciente.
+func (s *PostgresStore) GetVideoRevisions(videoID int) ([]*models.VideoRevision, error) {
+	query := `SELECT r.video_id, r.rev, r.title, COALESCE(r.description, ''), r.category, r.tags, r.author_id,
+            COALESCE(u.username, ''), r.created_at
+        FROM video_revisions r LEFT JOIN users u ON u.id = r.author_id
+        WHERE r.video_id = $1 ORDER BY r.rev`
+	rows, err := s.db.Query(query, videoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	revisions := []*models.VideoRevision{}
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *PostgresStore) GetVideoRevision(videoID, rev int) (*models.VideoRevision, error) {
+	query := `SELECT r.video_id, r.rev, r.title, COALESCE(r.description, ''), r.category, r.tags, r.author_id,
+            COALESCE(u.username, ''), r.created_at
+        FROM video_revisions r LEFT JOIN users u ON u.id = r.author_id
+        WHERE r.video_id = $1 AND r.rev = $2`
+	revision, err := scanRevision(s.db.QueryRow(query, videoID, rev))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("revisión no encontrada")
+		}
+		return nil, err
+	}
+	return revision, nil
+}
+
+func scanRevision(row rowScanner) (*models.VideoRevision, error) {
+	rev := new(models.VideoRevision)
+	var authorID sql.NullInt64
+	err := row.Scan(&rev.VideoID, &rev.Rev, &rev.Title, &rev.Description, &rev.Category, pq.Array(&rev.Tags),
+		&authorID, &rev.AuthorName, &rev.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if authorID.Valid {
+		id := int(authorID.Int64)
+		rev.AuthorID = &id
+	}
+	return rev, nil
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+
+	"streamvault/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// maxMatchesPerVideo limita cuántos momentos de la transcripción se devuelven por video.
+const maxMatchesPerVideo = 20
+
+// likeEscaper escapa los comodines de LIKE para que la frase se busque tal cual.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchVideos busca la frase en las transcripciones (cues de subtítulos) y en el título y la
+// descripción de los videos. Los resultados se ordenan por cantidad de coincidencias en la
+// transcripción, y los que solo coinciden por título o descripción van al final. Con
+// 'onlyAvailable' se excluyen los videos fuera de su ventana de publicación antes de aplicar
+// 'limit', para que quien no es admin reciba páginas completas.
+func (s *PostgresStore) SearchVideos(phrase string, limit int, onlyAvailable bool) ([]*models.SearchResult, error) {
+	matches := make(map[int][]models.TranscriptCue)
+	var order []int
+	visible := videoNotTrashedSQL
+	if onlyAvailable {
+		visible += ` AND ` + videoAvailableSQL
+	}
+
+	cueQuery := `SELECT c.video_id, c.lang, c.start_ms, c.end_ms, c.text FROM subtitle_cues c
+        JOIN videos v ON v.id = c.video_id
+        WHERE c.tsv @@ phraseto_tsquery('simple', $1) AND ` + visible + `
+        ORDER BY c.video_id, c.start_ms
+        LIMIT 2000`
+	rows, err := s.db.Query(cueQuery, phrase)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var videoID int
+		var startMs, endMs int64
+		var cue models.TranscriptCue
+		if err := rows.Scan(&videoID, &cue.Lang, &startMs, &endMs, &cue.Text); err != nil {
+			return nil, err
+		}
+		cue.Start, cue.End = float64(startMs)/1000, float64(endMs)/1000
+		if _, seen := matches[videoID]; !seen {
+			order = append(order, videoID)
+		}
+		if len(matches[videoID]) < maxMatchesPerVideo {
+			matches[videoID] = append(matches[videoID], cue)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(order, func(i, j int) bool { return len(matches[order[i]]) > len(matches[order[j]]) })
+
+	textQuery := `SELECT v.id FROM videos v
+        WHERE (v.title ILIKE '%' || $1 || '%' ESCAPE '\' OR v.description ILIKE '%' || $1 || '%' ESCAPE '\') AND ` + visible + `
+        ORDER BY v.uploaded_at DESC
+        LIMIT $2`
+	textRows, err := s.db.Query(textQuery, likeEscaper.Replace(phrase), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer textRows.Close()
+	for textRows.Next() {
+		var id int
+		if err := textRows.Scan(&id); err != nil {
+			return nil, err
+		}
+		if _, seen := matches[id]; !seen {
+			matches[id] = []models.TranscriptCue{}
+			order = append(order, id)
+		}
+	}
+	if err := textRows.Err(); err != nil {
+		return nil, err
+	}
+	if len(order) > limit {
+		order = order[:limit]
+	}
+
+	videoRows, err := s.db.Query(`SELECT `+videoColumns+` FROM videos v WHERE v.id = ANY($1) AND `+visible, pq.Array(order))
+	if err != nil {
+		return nil, err
+	}
+	defer videoRows.Close()
+	videos := make(map[int]*models.Video)
+	for videoRows.Next() {
+		video, err := scanVideo(videoRows)
+		if err != nil {
+			return nil, err
+		}
+		videos[video.ID] = video
+	}
+	if err := videoRows.Err(); err != nil {
+		return nil, err
+	}
+
+	results := []*models.SearchResult{}
+	for _, id := range order {
+		if video, ok := videos[id]; ok {
+			results = append(results, &models.SearchResult{Video: video, Matches: matches[id]})
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"time"
+)
+
+// createUsedUploadURLsTableSQL registra los enlaces de subida firmados que ya se usaron,
+// para que cada uno sirva para un solo archivo.
+const createUsedUploadURLsTableSQL = `
+    CREATE TABLE IF NOT EXISTS used_upload_urls (
+        nonce VARCHAR(64) PRIMARY KEY,
+        expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+    );`
+
+// ClaimUploadURL marca como usado el enlace de subida identificado por 'nonce'. Devuelve false
+// si ya se había usado. El registro se conserva hasta 'expiresAt', cuando el enlace ya no sirve.
+func (s *PostgresStore) ClaimUploadURL(nonce string, expiresAt time.Time) (bool, error) {
+	res, err := s.db.Exec(`INSERT INTO used_upload_urls (nonce, expires_at) VALUES ($1, $2)
+        ON CONFLICT (nonce) DO NOTHING`, nonce, expiresAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ReleaseUploadURL vuelve a habilitar un enlace de subida cuya subida falló.
+func (s *PostgresStore) ReleaseUploadURL(nonce string) error {
+	_, err := s.db.Exec(`DELETE FROM used_upload_urls WHERE nonce = $1`, nonce)
+	return err
+}
+
+// PurgeUsedUploadURLs borra los registros de enlaces de subida vencidos antes de 'before'.
+func (s *PostgresStore) PurgeUsedUploadURLs(before time.Time) (int64, error) {
+	res, err := s.db.Exec(`DELETE FROM used_upload_urls WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+
+	"streamvault/internal/models"
+)
+
+// createSubtitleTracksTableSQL guarda las pistas de subtítulos ya convertidas a WebVTT.
+// Cada video tiene como máximo una pista por idioma.
+const createSubtitleTracksTableSQL = `
+    CREATE TABLE IF NOT EXISTS subtitle_tracks (
+        video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+        lang VARCHAR(35) NOT NULL,
+        label VARCHAR(100) NOT NULL,
+        cue_count INTEGER NOT NULL,
+        content TEXT NOT NULL,
+        created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (video_id, lang)
+    );`
+
+// createSubtitleCuesTableSQL indexa el texto de cada cue para la búsqueda en transcripciones.
+// Se usa la configuración 'simple' de búsqueda de texto porque las pistas pueden estar en cualquier idioma.
+const createSubtitleCuesTableSQL = `
+    CREATE TABLE IF NOT EXISTS subtitle_cues (
+        video_id INTEGER NOT NULL,
+        lang VARCHAR(35) NOT NULL,
+        idx INTEGER NOT NULL,
+        start_ms BIGINT NOT NULL,
+        end_ms BIGINT NOT NULL,
+        text TEXT NOT NULL,
+        tsv TSVECTOR GENERATED ALWAYS AS (to_tsvector('simple', text)) STORED,
+        PRIMARY KEY (video_id, lang, idx),
+        FOREIGN KEY (video_id, lang) REFERENCES subtitle_tracks(video_id, lang) ON DELETE CASCADE
+    );
+    CREATE INDEX IF NOT EXISTS subtitle_cues_tsv_idx ON subtitle_cues USING GIN (tsv);`
+
+// SaveSubtitleTrack crea la pista o reemplaza la existente para el mismo idioma, y reemplaza
+// en la misma transacción los cues indexados para la búsqueda.
+func (s *PostgresStore) SaveSubtitleTrack(track *models.SubtitleTrack, cues []models.TranscriptCue) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	query := `INSERT INTO subtitle_tracks (video_id, lang, label, cue_count, content) VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (video_id, lang) DO UPDATE SET
+            label = EXCLUDED.label, cue_count = EXCLUDED.cue_count, content = EXCLUDED.content, created_at = NOW()
+        RETURNING created_at`
+	err = tx.QueryRow(query, track.VideoID, track.Lang, track.Label, track.CueCount, track.Content).Scan(&track.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if err := replaceSubtitleCues(tx, track.VideoID, track.Lang, cues); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// IndexSubtitleCues reemplaza los cues indexados de una pista existente sin tocar la pista.
+func (s *PostgresStore) IndexSubtitleCues(videoID int, lang string, cues []models.TranscriptCue) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := replaceSubtitleCues(tx, videoID, lang, cues); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceSubtitleCues(tx *sql.Tx, videoID int, lang string, cues []models.TranscriptCue) error {
+	if _, err := tx.Exec(`DELETE FROM subtitle_cues WHERE video_id = $1 AND lang = $2`, videoID, lang); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO subtitle_cues (video_id, lang, idx, start_ms, end_ms, text) VALUES ($1, $2, $3, $4, $5, $6)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for i, cue := range cues {
+		if _, err := stmt.Exec(videoID, lang, i, int64(cue.Start*1000), int64(cue.End*1000), cue.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetUnindexedSubtitleTracks devuelve, con su contenido, las pistas que todavía no tienen cues
+// indexados (por ejemplo, las subidas antes de que existiera la búsqueda en transcripciones).
+func (s *PostgresStore) GetUnindexedSubtitleTracks() ([]*models.SubtitleTrack, error) {
+	query := `SELECT t.video_id, t.lang, t.label, t.cue_count, t.content, t.created_at FROM subtitle_tracks t
+        WHERE NOT EXISTS (SELECT 1 FROM subtitle_cues c WHERE c.video_id = t.video_id AND c.lang = t.lang)`
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tracks []*models.SubtitleTrack
+	for rows.Next() {
+		t := new(models.SubtitleTrack)
+		if err := rows.Scan(&t.VideoID, &t.Lang, &t.Label, &t.CueCount, &t.Content, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, rows.Err()
+}
+
+// GetSubtitleTracks lista las pistas de un video sin su contenido.
+func (s *PostgresStore) GetSubtitleTracks(videoID int) ([]models.SubtitleTrack, error) {
+	query := `SELECT video_id, lang, label, cue_count, created_at FROM subtitle_tracks WHERE video_id = $1 ORDER BY lang`
+	rows, err := s.db.Query(query, videoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tracks := []models.SubtitleTrack{}
+	for rows.Next() {
+		var t models.SubtitleTrack
+		if err := rows.Scan(&t.VideoID, &t.Lang, &t.Label, &t.CueCount, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, t)
+	}
+	return tracks, rows.Err()
+}
+
+func (s *PostgresStore) GetSubtitleTrack(videoID int, lang string) (*models.SubtitleTrack, error) {
+	t := new(models.SubtitleTrack)
+	query := `SELECT video_id, lang, label, cue_count, content, created_at FROM subtitle_tracks WHERE video_id = $1 AND lang = $2`
+	err := s.db.QueryRow(query, videoID, lang).Scan(&t.VideoID, &t.Lang, &t.Label, &t.CueCount, &t.Content, &t.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("subtítulo no encontrado")
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// DeleteSubtitleTrack elimina una pista. Devuelve false si no existía.
+func (s *PostgresStore) DeleteSubtitleTrack(videoID int, lang string) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM subtitle_tracks WHERE video_id = $1 AND lang = $2`, videoID, lang)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
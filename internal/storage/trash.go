@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"streamvault/internal/models"
+)
+
+// TrashVideo mueve un video a la papelera. Deja de aparecer en listados y streaming,
+// pero sus datos y su archivo se conservan hasta la purga.
+func (s *PostgresStore) TrashVideo(id int) error {
+	res, err := s.db.Exec(`UPDATE videos SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("video no encontrado")
+	}
+	return nil
+}
+
+// RestoreVideo saca un video de la papelera.
+func (s *PostgresStore) RestoreVideo(id int) error {
+	res, err := s.db.Exec(`UPDATE videos SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("el video no está en la papelera")
+	}
+	return nil
+}
+
+// GetTrashedVideos devuelve los videos de la papelera, los borrados más recientemente primero.
+func (s *PostgresStore) GetTrashedVideos() ([]*models.Video, error) {
+	query := `SELECT ` + videoColumns + ` FROM videos v WHERE v.deleted_at IS NOT NULL ORDER BY v.deleted_at DESC`
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	videos := []*models.Video{}
+	for rows.Next() {
+		video, err := scanVideo(rows)
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+	return videos, rows.Err()
+}
+
+// PurgeTrashedVideos elimina definitivamente los videos que entraron a la papelera antes de 'before'
+// y los devuelve para que quien llama borre sus archivos. Los datos asociados (marcadores,
+// subtítulos, capítulos, etc.) se eliminan en cascada.
+func (s *PostgresStore) PurgeTrashedVideos(before time.Time) ([]*models.Video, error) {
+	query := `DELETE FROM videos v WHERE v.deleted_at IS NOT NULL AND v.deleted_at < $1 RETURNING ` + videoColumns
+	rows, err := s.db.Query(query, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var videos []*models.Video
+	for rows.Next() {
+		video, err := scanVideo(rows)
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+	return videos, rows.Err()
+}
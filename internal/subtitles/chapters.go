@@ -0,0 +1,76 @@
+package subtitles
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Chapter es un capítulo leído de la descripción de un video.
+type Chapter struct {
+	Start time.Duration
+	Title string
+}
+
+// MaxChapterTitleLen es la longitud máxima (en caracteres) del título de un capítulo.
+const MaxChapterTitleLen = 255
+
+// chapterLineRe reconoce líneas como "00:00 Intro", "1:02:03 - Conclusión" o "[12:30] Preguntas".
+var chapterLineRe = regexp.MustCompile(`^\s*\[?(?:(\d{1,2}):)?(\d{1,2}):(\d{2})\]?\s*[-–—:|]?\s*(\S.*)$`)
+
+// ParseDescriptionChapters extrae capítulos de las líneas de una descripción que empiezan con una
+// marca de tiempo. Siguiendo la convención habitual, solo se aceptan si hay al menos dos, el primero
+// empieza en 0:00 y están en orden creciente; si no, se devuelve nil y la descripción se ignora.
+func ParseDescriptionChapters(description string) []Chapter {
+	var chapters []Chapter
+	for _, line := range strings.Split(normalize([]byte(description)), "\n") {
+		m := chapterLineRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		hours, _ := strconv.Atoi(m[1])
+		minutes, _ := strconv.Atoi(m[2])
+		seconds, _ := strconv.Atoi(m[3])
+		if seconds > 59 || (m[1] != "" && minutes > 59) {
+			continue
+		}
+		start := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+		if len(chapters) > 0 && start <= chapters[len(chapters)-1].Start {
+			return nil
+		}
+		chapters = append(chapters, Chapter{Start: start, Title: CleanChapterTitle(m[4])})
+	}
+	if len(chapters) < 2 || chapters[0].Start != 0 {
+		return nil
+	}
+	return chapters
+}
+
+// CleanChapterTitle deja un título apto para una cue WebVTT y para guardarlo: reemplaza "-->" y
+// los saltos de línea, que cortarían la cue, y lo recorta a MaxChapterTitleLen caracteres.
+func CleanChapterTitle(title string) string {
+	title = strings.ReplaceAll(title, "-->", "→")
+	title = strings.Join(strings.Fields(title), " ")
+	if runes := []rune(title); len(runes) > MaxChapterTitleLen {
+		title = strings.TrimSpace(string(runes[:MaxChapterTitleLen]))
+	}
+	return title
+}
+
+// ChaptersVTT escribe los capítulos como una pista WebVTT de tipo "chapters". Cada capítulo termina
+// donde empieza el siguiente; el último termina en 'duration', o si se desconoce (0), se extiende
+// hasta el máximo que admite el formato para cubrir el resto del video.
+func ChaptersVTT(chapters []Chapter, duration time.Duration) string {
+	cues := make([]Cue, 0, len(chapters))
+	for i, ch := range chapters {
+		end := duration
+		if i+1 < len(chapters) {
+			end = chapters[i+1].Start
+		} else if end <= ch.Start {
+			end = 99*time.Hour + 59*time.Minute + 59*time.Second + 999*time.Millisecond
+		}
+		cues = append(cues, Cue{Start: ch.Start, End: end, Text: CleanChapterTitle(ch.Title)})
+	}
+	return FormatVTT(cues)
+}
@@ -0,0 +1,244 @@
+// El paquete 'subtitles' lee subtítulos en formato SRT o WebVTT, valida sus cues
+// y los vuelve a escribir como WebVTT, el único formato que entienden los navegadores.
+package subtitles
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cue es un fragmento de subtítulo que se muestra entre Start y End.
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	// Settings son los ajustes de posición de WebVTT (ej: "line:0 align:start"); en SRT van vacíos.
+	Settings string
+	Text     string
+}
+
+var (
+	// timestampRe acepta "hh:mm:ss,mmm" (SRT), "hh:mm:ss.mmm" y "mm:ss.mmm" (WebVTT).
+	timestampRe = regexp.MustCompile(`^(?:(\d+):)?(\d{2}):(\d{2})[.,](\d{3})$`)
+	// Etiquetas de SRT que WebVTT no soporta: <font ...> y los códigos de estilo de ASS como {\an8}.
+	fontTagRe = regexp.MustCompile(`(?i)</?font[^>]*>`)
+	assTagRe  = regexp.MustCompile(`\{\\[^}]*\}`)
+	// bareAmpRe encuentra los '&' que no inician una entidad HTML, que WebVTT exige escapar.
+	bareAmpRe = regexp.MustCompile(`&([^#a-zA-Z]|$)`)
+	// anyTagRe encuentra cualquier etiqueta de WebVTT (<b>, <v Ana>, <00:01.000>, etc.).
+	anyTagRe = regexp.MustCompile(`<[^>]*>`)
+	entities = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&nbsp;", " ", "&lrm;", "", "&rlm;", "")
+)
+
+// Parse detecta el formato por su encabezado (WebVTT empieza con "WEBVTT") y devuelve los cues validados.
+func Parse(data []byte) ([]Cue, error) {
+	text := normalize(data)
+	if strings.HasPrefix(text, "WEBVTT") {
+		return ParseVTT(text)
+	}
+	return ParseSRT(text)
+}
+
+// ParseSRT interpreta un archivo SubRip: bloques separados por una línea vacía, cada uno con
+// un número opcional, la línea de tiempos y el texto.
+func ParseSRT(text string) ([]Cue, error) {
+	var cues []Cue
+	for i, block := range splitBlocks(normalize([]byte(text))) {
+		lines := strings.Split(block, "\n")
+		if len(lines) > 0 && !strings.Contains(lines[0], "-->") {
+			if _, err := strconv.Atoi(strings.TrimSpace(lines[0])); err == nil {
+				lines = lines[1:]
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		cue, err := parseTiming(lines[0])
+		if err != nil {
+			return nil, fmt.Errorf("bloque %d: %w", i+1, err)
+		}
+		cue.Settings = ""
+		cue.Text = cleanSRTText(strings.Join(lines[1:], "\n"))
+		cues = append(cues, cue)
+	}
+	// Muchos SRT vienen con los bloques desordenados; al convertirlos se ordenan en lugar de rechazarlos.
+	SortCues(cues)
+	return cues, Validate(cues)
+}
+
+// ParseVTT interpreta un archivo WebVTT, ignorando los bloques NOTE, STYLE y REGION.
+func ParseVTT(text string) ([]Cue, error) {
+	blocks := splitBlocks(normalize([]byte(text)))
+	if len(blocks) == 0 || !strings.HasPrefix(blocks[0], "WEBVTT") {
+		return nil, fmt.Errorf("falta el encabezado WEBVTT")
+	}
+	var cues []Cue
+	for i, block := range blocks[1:] {
+		if strings.HasPrefix(block, "NOTE") || strings.HasPrefix(block, "STYLE") || strings.HasPrefix(block, "REGION") {
+			continue
+		}
+		lines := strings.Split(block, "\n")
+		// La primera línea puede ser un identificador del cue.
+		if !strings.Contains(lines[0], "-->") {
+			lines = lines[1:]
+		}
+		if len(lines) == 0 {
+			return nil, fmt.Errorf("bloque %d: falta la línea de tiempos", i+2)
+		}
+		cue, err := parseTiming(lines[0])
+		if err != nil {
+			return nil, fmt.Errorf("bloque %d: %w", i+2, err)
+		}
+		cue.Text = strings.Join(lines[1:], "\n")
+		cues = append(cues, cue)
+	}
+	return cues, Validate(cues)
+}
+
+// Validate comprueba que cada cue tenga texto y tiempos coherentes. Los cues pueden solaparse,
+// pero deben estar ordenados por inicio, como exige WebVTT.
+func Validate(cues []Cue) error {
+	if len(cues) == 0 {
+		return fmt.Errorf("el archivo no contiene subtítulos")
+	}
+	for i, cue := range cues {
+		switch {
+		case cue.Start < 0:
+			return fmt.Errorf("cue %d: el inicio es negativo", i+1)
+		case cue.End <= cue.Start:
+			return fmt.Errorf("cue %d: el final (%s) debe ser posterior al inicio (%s)", i+1, formatTimestamp(cue.End), formatTimestamp(cue.Start))
+		case strings.TrimSpace(cue.Text) == "":
+			return fmt.Errorf("cue %d: no tiene texto", i+1)
+		case strings.Contains(cue.Text, "-->"):
+			return fmt.Errorf("cue %d: el texto no puede contener '-->'", i+1)
+		case i > 0 && cue.Start < cues[i-1].Start:
+			return fmt.Errorf("cue %d: empieza antes que el cue anterior", i+1)
+		}
+	}
+	return nil
+}
+
+// SortCues ordena los cues por inicio (y por final en caso de empate), para reparar archivos
+// SRT desordenados antes de validarlos.
+func SortCues(cues []Cue) {
+	sort.SliceStable(cues, func(i, j int) bool {
+		if cues[i].Start != cues[j].Start {
+			return cues[i].Start < cues[j].Start
+		}
+		return cues[i].End < cues[j].End
+	})
+}
+
+// FormatVTT escribe los cues como un documento WebVTT completo.
+func FormatVTT(cues []Cue) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for _, cue := range cues {
+		b.WriteString("\n")
+		b.WriteString(formatTimestamp(cue.Start))
+		b.WriteString(" --> ")
+		b.WriteString(formatTimestamp(cue.End))
+		if cue.Settings != "" {
+			b.WriteString(" ")
+			b.WriteString(cue.Settings)
+		}
+		b.WriteString("\n")
+		b.WriteString(cue.Text)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// PlainText devuelve el texto de un cue sin etiquetas ni entidades, en una sola línea,
+// listo para indexarlo o mostrarlo como extracto.
+func PlainText(text string) string {
+	text = entities.Replace(anyTagRe.ReplaceAllString(text, ""))
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// normalize quita el BOM y unifica los saltos de línea a "\n".
+func normalize(data []byte) string {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	return strings.ReplaceAll(text, "\r", "\n")
+}
+
+// splitBlocks separa el texto en bloques delimitados por líneas vacías.
+func splitBlocks(text string) []string {
+	var blocks []string
+	var current []string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t")
+		if line == "" {
+			if len(current) > 0 {
+				blocks = append(blocks, strings.Join(current, "\n"))
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		blocks = append(blocks, strings.Join(current, "\n"))
+	}
+	return blocks
+}
+
+// parseTiming lee una línea "inicio --> final [ajustes]".
+func parseTiming(line string) (Cue, error) {
+	parts := strings.SplitN(line, "-->", 2)
+	if len(parts) != 2 {
+		return Cue{}, fmt.Errorf("línea de tiempos inválida: %q", line)
+	}
+	start, err := parseTimestamp(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return Cue{}, err
+	}
+	rest := strings.Fields(parts[1])
+	if len(rest) == 0 {
+		return Cue{}, fmt.Errorf("línea de tiempos inválida: %q", line)
+	}
+	end, err := parseTimestamp(rest[0])
+	if err != nil {
+		return Cue{}, err
+	}
+	return Cue{Start: start, End: end, Settings: strings.Join(rest[1:], " ")}, nil
+}
+
+// parseTimestamp interpreta una marca de tiempo de SRT o WebVTT.
+func parseTimestamp(value string) (time.Duration, error) {
+	m := timestampRe.FindStringSubmatch(value)
+	if m == nil {
+		return 0, fmt.Errorf("marca de tiempo inválida: %q", value)
+	}
+	hours, _ := strconv.Atoi(m[1])
+	minutes, _ := strconv.Atoi(m[2])
+	seconds, _ := strconv.Atoi(m[3])
+	millis, _ := strconv.Atoi(m[4])
+	if minutes > 59 || seconds > 59 {
+		return 0, fmt.Errorf("marca de tiempo inválida: %q", value)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second + time.Duration(millis)*time.Millisecond, nil
+}
+
+// formatTimestamp escribe una duración como "hh:mm:ss.mmm".
+func formatTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// cleanSRTText adapta el texto de SRT a WebVTT: quita las etiquetas que WebVTT no soporta
+// y escapa los '&' sueltos.
+func cleanSRTText(text string) string {
+	text = fontTagRe.ReplaceAllString(text, "")
+	text = assTagRe.ReplaceAllString(text, "")
+	return bareAmpRe.ReplaceAllString(text, "&amp;$1")
+}
@@ -0,0 +1,77 @@
+//go:build linux
+
+package watchfolder
+
+import (
+	"log"
+	"sync"
+	"syscall"
+)
+
+// inotifyEvents son los cambios que provocan un nuevo recorrido: archivos o directorios
+// creados, archivos que se terminaron de escribir y archivos movidos a la carpeta.
+const inotifyEvents = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO
+
+// inotifyNotifier implementa notifier con inotify. inotify no es recursivo, así que se agrega
+// un watch por cada directorio que encuentra el recorrido.
+type inotifyNotifier struct {
+	fd int
+	ch chan struct{}
+
+	mu      sync.Mutex
+	watches map[string]bool
+}
+
+func newNotifier() (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	n := &inotifyNotifier{fd: fd, ch: make(chan struct{}, 1), watches: make(map[string]bool)}
+	go n.read()
+	return n, nil
+}
+
+func (n *inotifyNotifier) events() <-chan struct{} { return n.ch }
+
+func (n *inotifyNotifier) watch(dirs []string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	current := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		current[dir] = true
+		if n.watches[dir] {
+			continue
+		}
+		if _, err := syscall.InotifyAddWatch(n.fd, dir, inotifyEvents); err != nil {
+			log.Printf("Carpeta vigilada: no se pudo vigilar %s con inotify: %v", dir, err)
+			continue
+		}
+		n.watches[dir] = true
+	}
+	// El kernel quita solo el watch de un directorio borrado; si se vuelve a crear, se agrega de nuevo.
+	for dir := range n.watches {
+		if !current[dir] {
+			delete(n.watches, dir)
+		}
+	}
+}
+
+// read espera eventos de inotify. El contenido no importa: cualquier evento provoca un recorrido,
+// y varios eventos seguidos se juntan en un solo aviso.
+func (n *inotifyNotifier) read() {
+	buf := make([]byte, 64<<10)
+	for {
+		if _, err := syscall.Read(n.fd, buf); err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			log.Printf("Carpeta vigilada: inotify dejó de funcionar, se sigue revisando periódicamente: %v", err)
+			return
+		}
+		select {
+		case n.ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package watchfolder
+
+import "errors"
+
+// En otros sistemas no hay notificaciones: la carpeta solo se revisa periódicamente.
+func newNotifier() (notifier, error) {
+	return nil, errors.New("inotify no está disponible en este sistema")
+}
@@ -0,0 +1,223 @@
+package watchfolder
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// sidecarExtensions son las extensiones de los archivos de metadatos, en orden de preferencia.
+var sidecarExtensions = []string{".json", ".yaml", ".yml"}
+
+// Metadata son los metadatos de un video tomados de su sidecar. Los campos ausentes quedan vacíos.
+type Metadata struct {
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Category    string  `json:"category"`
+	Tags        tagList `json:"tags"`
+	PublishAt   string  `json:"publish_at"`
+	ExpireAt    string  `json:"expire_at"`
+}
+
+// tagList acepta las etiquetas como lista o como texto separado por comas.
+type tagList []string
+
+func (t *tagList) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*t = strings.Split(text, ",")
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("'tags' debe ser una lista o un texto separado por comas")
+	}
+	*t = list
+	return nil
+}
+
+// IsSidecar indica si 'name' tiene la extensión de un archivo de metadatos.
+func IsSidecar(name string) bool {
+	return slices.Contains(sidecarExtensions, strings.ToLower(filepath.Ext(name)))
+}
+
+// ReadSidecar busca junto al video un archivo con el mismo nombre y extensión .json, .yaml o
+// .yml, y lee sus metadatos. Devuelve también la ruta del sidecar, o "" si no hay ninguno (en
+// ese caso los metadatos están vacíos y no es un error).
+func ReadSidecar(videoPath string) (*Metadata, string, error) {
+	base := strings.TrimSuffix(videoPath, filepath.Ext(videoPath))
+	for _, ext := range sidecarExtensions {
+		path := base + ext
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, path, err
+		}
+		meta := &Metadata{}
+		if ext == ".json" {
+			err = json.Unmarshal(data, meta)
+		} else {
+			err = parseYAML(data, meta)
+		}
+		if err != nil {
+			return nil, path, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		return meta, path, nil
+	}
+	return &Metadata{}, "", nil
+}
+
+// parseYAML entiende el subconjunto de YAML que se usa en un sidecar: pares "clave: valor" en el
+// primer nivel, con valores entre comillas o sin ellas, listas "[a, b]" o con líneas "- a", y
+// textos de varias líneas con "|". Las claves desconocidas se ignoran.
+func parseYAML(data []byte, meta *Metadata) error {
+	fields := map[string]*string{
+		"title": &meta.Title, "description": &meta.Description, "category": &meta.Category,
+		"publish_at": &meta.PublishAt, "expire_at": &meta.ExpireAt,
+	}
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), " \t\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if line != trimmed {
+			return fmt.Errorf("línea %d: se esperaba una clave del primer nivel", i+1)
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("línea %d: falta ':'", i+1)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		// Las líneas con más sangría que siguen a la clave son su contenido (texto o lista).
+		var block []string
+		for i+1 < len(lines) && (lines[i+1] == "" || lines[i+1][0] == ' ' || lines[i+1][0] == '\t') {
+			i++
+			block = append(block, lines[i])
+		}
+		switch {
+		case key == "tags":
+			tags, err := yamlList(value, block)
+			if err != nil {
+				return fmt.Errorf("línea %d: %w", i+1, err)
+			}
+			meta.Tags = tags
+		case fields[key] != nil:
+			text, err := yamlScalar(value, block)
+			if err != nil {
+				return fmt.Errorf("clave '%s': %w", key, err)
+			}
+			*fields[key] = text
+		}
+	}
+	return nil
+}
+
+// yamlScalar interpreta el valor de una clave: un texto en la misma línea (con o sin comillas)
+// o un bloque "|" (conserva los saltos de línea) o ">" (los une con espacios).
+func yamlScalar(value string, block []string) (string, error) {
+	if value == "|" || value == ">" {
+		text := strings.Join(dedent(block), "\n")
+		if value == ">" {
+			text = strings.Join(strings.Fields(text), " ")
+		}
+		return strings.TrimRight(text, "\n"), nil
+	}
+	if strings.TrimSpace(strings.Join(block, "")) != "" {
+		return "", errors.New("texto de varias líneas sin '|'")
+	}
+	return unquote(stripComment(value)), nil
+}
+
+// yamlList interpreta una lista en la misma línea ("[a, b]"), como líneas "- a", o un texto
+// separado por comas.
+func yamlList(value string, block []string) ([]string, error) {
+	value = stripComment(value)
+	if strings.HasPrefix(value, "[") {
+		if !strings.HasSuffix(value, "]") {
+			return nil, errors.New("lista sin cerrar")
+		}
+		var items []string
+		for _, item := range strings.Split(value[1:len(value)-1], ",") {
+			items = append(items, unquote(strings.TrimSpace(item)))
+		}
+		return items, nil
+	}
+	if value != "" {
+		return strings.Split(unquote(value), ","), nil
+	}
+	var items []string
+	for _, line := range block {
+		item := strings.TrimSpace(line)
+		if item == "" || strings.HasPrefix(item, "#") {
+			continue
+		}
+		rest, ok := strings.CutPrefix(item, "-")
+		if !ok {
+			return nil, fmt.Errorf("se esperaba '- elemento' en %q", item)
+		}
+		items = append(items, unquote(stripComment(strings.TrimSpace(rest))))
+	}
+	return items, nil
+}
+
+// dedent quita de las líneas del bloque la sangría de la primera línea no vacía.
+func dedent(block []string) []string {
+	indent := ""
+	for _, line := range block {
+		if strings.TrimSpace(line) != "" {
+			indent = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			break
+		}
+	}
+	out := make([]string, len(block))
+	for i, line := range block {
+		out[i] = strings.TrimPrefix(line, indent)
+	}
+	return out
+}
+
+// stripComment quita un comentario " #..." al final de un valor sin comillas.
+func stripComment(value string) string {
+	if strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'") {
+		return value
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		return strings.TrimSpace(value[:i])
+	}
+	return value
+}
+
+// unquote quita las comillas simples o dobles que rodean un valor.
+func unquote(value string) string {
+	if len(value) >= 2 {
+		if value[0] == '"' && value[len(value)-1] == '"' {
+			var s string
+			if err := json.Unmarshal([]byte(value), &s); err == nil {
+				return s
+			}
+			return value[1 : len(value)-1]
+		}
+		if value[0] == '\'' && value[len(value)-1] == '\'' {
+			return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+		}
+	}
+	return value
+}
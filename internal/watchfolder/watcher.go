@@ -0,0 +1,139 @@
+// Package watchfolder vigila un directorio y avisa cuando aparece un archivo nuevo que ya
+// terminó de copiarse. Recorre el directorio periódicamente y, donde está disponible (Linux),
+// usa inotify para enterarse antes de los cambios.
+package watchfolder
+
+import (
+	"io/fs"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// notifier avisa de cambios en los directorios vigilados. Cada aviso solo indica que conviene
+// volver a recorrer el directorio.
+type notifier interface {
+	// watch actualiza la lista de directorios vigilados.
+	watch(dirs []string)
+	events() <-chan struct{}
+}
+
+// Watcher recorre Dir y sus subdirectorios y llama a Ready con cada archivo que dejó de crecer.
+type Watcher struct {
+	Dir string
+	// Skip son los subdirectorios de Dir que no se recorren (por ejemplo, a donde se mueven los
+	// archivos ya procesados).
+	Skip []string
+	// PollInterval es cada cuánto se recorre el directorio aunque no lleguen avisos.
+	PollInterval time.Duration
+	// SettleTime es cuánto tiempo debe pasar un archivo sin cambiar de tamaño ni de fecha de
+	// modificación para considerarlo completo.
+	SettleTime time.Duration
+	// Accept decide qué archivos se vigilan; los demás (como los sidecar) se ignoran.
+	Accept func(name string) bool
+	// Ready procesa un archivo completo. Debe sacarlo de Dir: mientras siga ahí no se vuelve a avisar.
+	Ready func(path string)
+
+	files map[string]*fileState
+}
+
+// fileState es lo último que se vio de un archivo.
+type fileState struct {
+	size    int64
+	modTime time.Time
+	// since es desde cuándo el archivo tiene este tamaño y esta fecha.
+	since time.Time
+	// done indica que ya se avisó del archivo.
+	done bool
+}
+
+// Run vigila el directorio sin terminar nunca.
+func (w *Watcher) Run() {
+	w.files = make(map[string]*fileState)
+	n, err := newNotifier()
+	if err != nil {
+		log.Printf("Carpeta vigilada %s: se revisa cada %s (%v)", w.Dir, w.PollInterval, err)
+	}
+	var events <-chan struct{}
+	if n != nil {
+		events = n.events()
+	}
+	for {
+		dirs, pending := w.scan()
+		if n != nil {
+			n.watch(dirs)
+		}
+		// Si hay archivos copiándose se vuelve a mirar en cuanto puedan estar completos.
+		wait := w.PollInterval
+		if pending && w.SettleTime < wait {
+			wait = w.SettleTime
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-events:
+			timer.Stop()
+		}
+	}
+}
+
+// scan recorre el directorio, actualiza el estado de cada archivo y avisa de los que ya están
+// completos. Devuelve los directorios recorridos y si queda algún archivo a la espera.
+func (w *Watcher) scan() (dirs []string, pending bool) {
+	now := time.Now()
+	present := make(map[string]bool)
+	err := filepath.WalkDir(w.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Un subdirectorio ilegible no impide recorrer el resto.
+			log.Printf("Carpeta vigilada: no se pudo leer %s: %v", path, err)
+			if d != nil && d.IsDir() && path != w.Dir {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			rel, _ := filepath.Rel(w.Dir, path)
+			if path != w.Dir && (strings.HasPrefix(name, ".") || slices.Contains(w.Skip, rel)) {
+				return fs.SkipDir
+			}
+			dirs = append(dirs, path)
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(name, ".") || (w.Accept != nil && !w.Accept(name)) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		present[path] = true
+		state := w.files[path]
+		if state == nil || state.size != info.Size() || !state.modTime.Equal(info.ModTime()) {
+			w.files[path] = &fileState{size: info.Size(), modTime: info.ModTime(), since: now}
+			pending = true
+			return nil
+		}
+		if state.done {
+			return nil
+		}
+		if now.Sub(state.since) < w.SettleTime {
+			pending = true
+			return nil
+		}
+		state.done = true
+		w.Ready(path)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Carpeta vigilada: error al recorrer %s: %v", w.Dir, err)
+	}
+	for path := range w.files {
+		if !present[path] {
+			delete(w.files, path)
+		}
+	}
+	return dirs, pending
+}